@@ -0,0 +1,33 @@
+package ewah
+
+// Accumulator computes the union of many bitmaps added one at a time. It
+// keeps two internal bitmaps that take turns to hold the union so far, so
+// after a few calls their words have enough capacity and adding a bitmap
+// allocates no memory. An Accumulator can be reused with Reset to keep
+// those buffers for the next union.
+type Accumulator struct {
+	acc, scratch *Bitmap
+}
+
+// NewAccumulator creates a new empty accumulator.
+func NewAccumulator() *Accumulator {
+	return &Accumulator{acc: New(), scratch: New()}
+}
+
+// Add adds the bits of b to the union.
+func (a *Accumulator) Add(b *Bitmap) {
+	OrTo(a.scratch, a.acc, b)
+	a.acc, a.scratch = a.scratch, a.acc
+}
+
+// Result returns a new bitmap with the union of all the bitmaps added so
+// far. The accumulator can still be used after calling it.
+func (a *Accumulator) Result() *Bitmap {
+	return a.acc.Clone()
+}
+
+// Reset empties the accumulator, keeping its buffers for reuse.
+func (a *Accumulator) Reset() {
+	a.acc.clear()
+	a.scratch.clear()
+}
@@ -0,0 +1,40 @@
+package ewah
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccumulator(t *testing.T) {
+	require := require.New(t)
+	r := rand.New(rand.NewSource(1))
+
+	acc := NewAccumulator()
+	require.Equal(New(), acc.Result())
+
+	var bitmaps []*Bitmap
+	for i := 0; i < 10; i++ {
+		b := fromPositions(randomPositions(r, int64(r.Intn(10000)), 0.3)...)
+		bitmaps = append(bitmaps, b)
+		acc.Add(b)
+	}
+
+	result := acc.Result()
+	require.Equal(positions(orMany(bitmaps...)), positions(result))
+
+	acc.Add(fromPositions(20000))
+	require.NotEqual(result.n, acc.Result().n)
+
+	acc.Reset()
+	require.Equal(New(), acc.Result())
+
+	allocs := testing.AllocsPerRun(10, func() {
+		acc.Reset()
+		for _, b := range bitmaps {
+			acc.Add(b)
+		}
+	})
+	require.Zero(allocs)
+}
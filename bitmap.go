@@ -7,32 +7,105 @@ import (
 	"fmt"
 	"io"
 	"math"
+	"sort"
+	"unsafe"
 )
 
 // Bitmap is an EWAH-encoded bitmap.
 // See: https://github.com/lemire/javaewah
 type Bitmap struct {
-	// n is the number of bits in the bitmap
+	// n is the number of bits in the bitmap, including the ones before base
 	n int64
+	// base is the position of the first bit encoded in the words, which is
+	// always a multiple of 64
+	base int64
 	// w is the list of words in the bitmap
 	w []uint64
 
 	// stuff for writing efficiently
 	lastrlw int
+	// prevrlw is the marker before lastrlw, or -1 if it is not known
+	prevrlw int
+	// lastoff is the position, relative to base, of the first bit covered
+	// by lastrlw
+	lastoff int64
+	// spare holds words that are not in use, kept so the operations that
+	// write to one of their operands can reuse them
+	spare []uint64
 
 	// stuff for reading efficiently
 	cursor  int
 	lastpos int64
 	acc     int64
+	// index points to some of the markers so lookups in frozen bitmaps
+	// don't need to scan the words from the start
+	index []skipEntry
+	// card is the number of set bits, which is only known if hasCard is set
+	card    int64
+	hasCard bool
+
+	// frozen bitmaps can't be modified
+	frozen bool
 }
 
 // New creates a new empty bitmap.
 func New() *Bitmap {
-	return &Bitmap{lastrlw: -1}
+	return &Bitmap{lastrlw: -1, prevrlw: -1}
+}
+
+// NewWithBase creates a new empty bitmap whose positions start at base, which
+// is rounded down to a multiple of 64. Positions lower than base can't be set
+// and take no space at all, which is useful when positions start at a very
+// large number. Apart from that, the bitmap behaves like any other and all
+// positions used with it are absolute.
+func NewWithBase(base int64) *Bitmap {
+	if base < 0 {
+		base = 0
+	}
+
+	base = base / 64 * 64
+	return &Bitmap{n: base, base: base, lastrlw: -1, prevrlw: -1}
+}
+
+// Base returns the position of the first bit that can be set in the bitmap.
+func (b *Bitmap) Base() int64 {
+	return b.base
 }
 
 // FromReader creates a Bitmap from the given reader.
 func FromReader(r io.Reader, order binary.ByteOrder) (*Bitmap, error) {
+	return FromReaderWithOptions(r, ReadOptions{Order: order})
+}
+
+// ReadOptions configures how a bitmap is read by FromReaderWithOptions.
+type ReadOptions struct {
+	// Order is the byte order used to read numbers.
+	Order binary.ByteOrder
+	// MaxWords, if positive, is the maximum number of words of the bitmap.
+	// Bitmaps with more words are rejected with ErrTooManyWords without
+	// reading any of them.
+	MaxWords int
+}
+
+// ErrTooManyWords is returned when a bitmap being read has more words than
+// allowed by ReadOptions.
+var ErrTooManyWords = errors.New("bitmap: too many words")
+
+// readChunk is the maximum number of words allocated before reading them, so
+// a corrupt word count can't make a reader allocate much more memory than
+// the data it actually reads needs.
+const readChunk = 1 << 16
+
+// FromReaderWithOptions creates a Bitmap from the given reader like
+// FromReader does, with the given options. Whatever the options, the word
+// count read is never trusted to allocate memory up front: bitmaps that
+// claim more words than the reader holds are rejected right away if the
+// reader knows how many bytes it has left, as io.LimitedReader, bytes.Reader
+// and bytes.Buffer do, and otherwise fail once the reader runs out of data.
+// A position of the current RLW that does not point to a marker is reported
+// with a CorruptError.
+func FromReaderWithOptions(r io.Reader, opts ReadOptions) (*Bitmap, error) {
+	order := opts.Order
 	bits, err := readUint32(r, order)
 	if err != nil {
 		return nil, fmt.Errorf("bitmap: can't read uncompressed bit number: %s", err)
@@ -43,12 +116,21 @@ func FromReader(r io.Reader, order binary.ByteOrder) (*Bitmap, error) {
 		return nil, fmt.Errorf("bitmap: can't read compressed word number: %s", err)
 	}
 
-	w := make([]uint64, int(words))
-	for i := 0; i < int(words); i++ {
-		w[i], err = readUint64(r, order)
+	if opts.MaxWords > 0 && int64(words) > int64(opts.MaxWords) {
+		return nil, fmt.Errorf("%w: bitmap has %d, but at most %d are allowed", ErrTooManyWords, words, opts.MaxWords)
+	}
+
+	if left, ok := bytesLeft(r); ok && int64(words)*8+4 > left {
+		return nil, fmt.Errorf("bitmap: can't read %d words from %d bytes: %w", words, left, io.ErrUnexpectedEOF)
+	}
+
+	w := make([]uint64, 0, minInt64(int64(words), readChunk))
+	for i := int64(0); i < int64(words); i++ {
+		word, err := readUint64(r, order)
 		if err != nil {
 			return nil, fmt.Errorf("bitmap: can't read %dth word: %s", i+1, err)
 		}
+		w = append(w, word)
 	}
 
 	lastrlw, err := readUint32(r, order)
@@ -56,11 +138,42 @@ func FromReader(r io.Reader, order binary.ByteOrder) (*Bitmap, error) {
 		return nil, fmt.Errorf("bitmap: can't read position of current RLW: %s", err)
 	}
 
-	return &Bitmap{
-		n:       int64(bits),
-		w:       w,
-		lastrlw: int(lastrlw),
-	}, nil
+	// bitmaps without words are written with a position of -1
+	last := int(lastrlw)
+	if lastrlw == math.MaxUint32 || len(w) == 0 {
+		last = -1
+	}
+
+	if last >= 0 && !isMarker(w, last) {
+		return nil, &CorruptError{last, "position of the current RLW is not a marker"}
+	}
+
+	b := &Bitmap{n: int64(bits), w: w}
+	b.setLast(last)
+	return b, nil
+}
+
+// bytesLeft returns the number of bytes left in the reader, if it is one of
+// the readers that know it.
+func bytesLeft(r io.Reader) (int64, bool) {
+	switch r := r.(type) {
+	case *countingReader:
+		return bytesLeft(r.r)
+	case *io.LimitedReader:
+		return r.N, true
+	case interface{ Len() int }:
+		return int64(r.Len()), true
+	}
+	return 0, false
+}
+
+// isMarker reports whether the word at index i is one of the markers of w.
+func isMarker(w []uint64, i int) bool {
+	j := 0
+	for j < i && j < len(w) {
+		j += int(rlw(w[j]).l()) + 1
+	}
+	return j == i && i < len(w)
 }
 
 // FromBytes creates a Bitmap from the given bytes.
@@ -71,25 +184,91 @@ func FromBytes(b []byte, order binary.ByteOrder) (*Bitmap, error) {
 // Write will write the Bitmap to a writer with the following format:
 // https://github.com/git/git/blob/master/Documentation/technical/bitmap-format.txt#L92
 func (b *Bitmap) Write(w io.Writer, order binary.ByteOrder) (n int64, err error) {
+	prefix := b.basePrefix()
+	words := len(prefix) + len(b.w)
+
 	if err := writeUint32(w, order, b.Bits()); err != nil {
 		return 0, err
 	}
 
-	if err := writeUint32(w, order, uint32(len(b.w))); err != nil {
+	if err := writeUint32(w, order, uint32(words)); err != nil {
 		return 0, err
 	}
 
+	for _, word := range prefix {
+		if err := writeUint64(w, order, word); err != nil {
+			return 0, err
+		}
+	}
+
 	for _, word := range b.w {
 		if err := writeUint64(w, order, word); err != nil {
 			return 0, err
 		}
 	}
 
-	if err := writeUint32(w, order, uint32(b.lastrlw)); err != nil {
+	lastrlw := b.lastrlw + len(prefix)
+	if b.lastrlw < 0 {
+		lastrlw = len(prefix) - 1
+	}
+
+	if err := writeUint32(w, order, uint32(lastrlw)); err != nil {
 		return 0, err
 	}
 
-	return 4*3 + int64(len(b.w))*8, nil
+	return 4*3 + int64(words)*8, nil
+}
+
+// basePrefix returns the markers needed to encode the positions before the
+// base of the bitmap, which have to be written before its words.
+func (b *Bitmap) basePrefix() []uint64 {
+	var prefix []uint64
+	for k := b.base / 64; k > 0; k -= math.MaxUint32 {
+		prefix = append(prefix, uint64(newRlw(false, uint32(minInt64(k, math.MaxUint32)), 0)))
+	}
+	return prefix
+}
+
+// WriteOptions configures how a bitmap is written by WriteWithOptions.
+type WriteOptions struct {
+	// Order is the byte order used to write numbers. Git uses
+	// binary.BigEndian.
+	Order binary.ByteOrder
+	// Align, if greater than 1, pads the output with zero bytes so it ends
+	// at a multiple of Align bytes, such as 4 or 8.
+	Align int
+	// Offset is the position at which the bitmap starts inside the container
+	// it is embedded in, so alignment is relative to the container instead of
+	// to the start of the bitmap.
+	Offset int64
+}
+
+// WriteWithOptions writes the bitmap like Write does, followed by the padding
+// required by the given options. Padding is not part of the format, but it is
+// ignored by readers such as FromReader.
+func (b *Bitmap) WriteWithOptions(w io.Writer, opts WriteOptions) (int64, error) {
+	n, err := b.Write(w, opts.Order)
+	if err != nil {
+		return n, err
+	}
+
+	if opts.Align <= 1 {
+		return n, nil
+	}
+
+	align := int64(opts.Align)
+	pad := (align - (opts.Offset+n)%align) % align
+	if pad < 0 {
+		pad += align
+	}
+
+	if pad > 0 {
+		if _, err := w.Write(make([]byte, pad)); err != nil {
+			return n, err
+		}
+	}
+
+	return n + pad, nil
 }
 
 func writeUint32(w io.Writer, bo binary.ByteOrder, num uint32) error {
@@ -149,68 +328,92 @@ var ErrInvalidBitSet = errors.New("bitmap: attempted to set a bit before the las
 const allones = ^uint64(0)
 const maxUint31 = ^uint32(0) >> 1
 
+// ErrFrozen is returned when there is an attempt to modify a frozen bitmap.
+var ErrFrozen = errors.New("bitmap: attempted to modify a frozen bitmap")
+
+// CorruptError is returned when the words of a bitmap are not consistent with
+// its metadata, which can only happen when it was decoded from damaged or
+// hostile data.
+type CorruptError struct {
+	// Word is the index of the offending word.
+	Word int
+	// Reason describes what is wrong with the word.
+	Reason string
+}
+
+func (e *CorruptError) Error() string {
+	return fmt.Sprintf("bitmap: corrupt word %d: %s", e.Word, e.Reason)
+}
+
+// checkTail checks that lastrlw points to the last marker and that its
+// literals are the last words, which is what Set relies on to append words.
+func (b *Bitmap) checkTail() error {
+	if b.lastrlw < 0 {
+		if len(b.w) > 0 || wordsFor(b.n) > b.base/64 {
+			return &CorruptError{b.lastrlw, "no marker to write to"}
+		}
+		return nil
+	}
+
+	if b.lastrlw >= len(b.w) {
+		return &CorruptError{b.lastrlw, fmt.Sprintf("last marker is out of %d words", len(b.w))}
+	}
+
+	if l := int(rlw(b.w[b.lastrlw]).l()); b.lastrlw+l+1 != len(b.w) {
+		return &CorruptError{b.lastrlw, fmt.Sprintf("last marker has %d literals, but is followed by %d words", l, len(b.w)-b.lastrlw-1)}
+	}
+	return nil
+}
+
 // Set sets to 1 the bit at the given position. Take into account that bits
 // need to be set in ascending order. Setting the 4th bit will return an error
-// if you already set the 5th bit, for example.
+// if you already set the 5th bit, for example, but setting a bit that is
+// already set has no effect, so sorted positions with duplicates can be set
+// as they come. Use SetAnywhere to set bits in any order.
 func (b *Bitmap) Set(pos int64) error {
-	if b.n > pos {
-		return ErrInvalidBitSet
+	if b.frozen {
+		return ErrFrozen
 	}
 
-	if b.lastrlw < 0 {
-		b.lastrlw = 0
-		b.w = append(b.w, uint64(newRlw(false, 0, 0)))
+	if b.n > pos {
+		if pos >= b.base && b.Get(pos) {
+			return nil
+		}
+		return ErrInvalidBitSet
 	}
 
-	last := len(b.w) - 1
-	lastrlw := rlw(b.w[b.lastrlw])
 	idx := uint64(pos % 64)
+	var literal uint64
+	setbit(&literal, idx)
 
-	bn := b.size()
+	if err := b.checkTail(); err != nil {
+		return err
+	}
 
 	// it's inside the last word
-	if bn > pos {
-		setbit(&b.w[last], idx)
-
-		// all bits in this literal are 1s, so transform it into a rlw
-		if b.w[last] == allones {
-			// previous rlw has 1 literal (the one being transformed), so
-			// remove the literal and increase k by 1 only if k does not overflow
-			if lastrlw.b() && lastrlw.l() == 1 && lastrlw.k() < math.MaxUint32 {
-				lastrlw.setk(lastrlw.k() + 1)
-				lastrlw.setl(0)
-				b.w[b.lastrlw] = uint64(lastrlw)
-				b.w = b.w[:last]
-			} else {
-				lastrlw.setl(lastrlw.l() - 1)
-				b.w[last] = uint64(newRlw(true, 1, 0))
-				b.w[b.lastrlw] = uint64(lastrlw)
-				b.lastrlw = last
+	if bn := b.size(); bn > pos {
+		last := len(b.w) - 1
+		lastrlw := rlw(b.w[b.lastrlw])
+		if lastrlw.l() > 0 {
+			setbit(&b.w[last], idx)
+			if b.hasCard {
+				b.card++
 			}
-		}
-	} else {
-		k := (pos - bn) / 64
-		var literal uint64
-		setbit(&literal, idx)
-
-		// increment l only if l does not overflow
-		if k == 0 && lastrlw.l()+1 <= maxUint31 {
-			lastrlw.setl(lastrlw.l() + 1)
-			b.w[b.lastrlw] = uint64(lastrlw)
-		} else if k > 0 && int64(lastrlw.k())+k <= math.MaxUint32 && lastrlw.l() == 0 {
-			// increment k only if k does not overflow
-			lastrlw.setk(lastrlw.k() + uint32(k))
-			lastrlw.setl(lastrlw.l() + 1)
-			b.w[b.lastrlw] = uint64(lastrlw)
+			b.foldLast()
 		} else {
-			b.w = append(b.w, uint64(newRlw(false, uint32(k-math.MaxUint32-1), 1)))
-			b.lastrlw = len(b.w) - 1
+			// the last word is the tail of a run of zeroes, so take it out
+			// of the run and turn it into a literal
+			lastrlw.setk(lastrlw.k() - 1)
+			b.w[b.lastrlw] = uint64(lastrlw)
+			b.appendLiteral(literal)
 		}
-
-		b.w = append(b.w, literal)
+	} else {
+		b.appendClean(false, (pos-bn)/64)
+		b.appendLiteral(literal)
 	}
 
 	b.n = pos + 1
+	b.assertInvariants()
 
 	return nil
 }
@@ -222,12 +425,30 @@ func (b *Bitmap) Get(pos int64) bool {
 		return false
 	}
 
+	// frozen bitmaps may be read by many goroutines at once, so they can't
+	// keep track of the last read
+	if b.frozen {
+		return b.lookup(pos)
+	}
+
+	if pos < b.base {
+		return false
+	}
+	pos -= b.base
+
+	// positions covered by the last marker, usually the ones set last, are
+	// read straight from it without moving the cursor
+	if bit, ok := b.getLast(pos); ok {
+		return bit
+	}
+
 	if b.lastpos > pos {
 		b.lastpos = -1
 		b.cursor = 0
 		b.acc = 0
 	} else if b.cursor >= len(b.w) {
-		b.cursor = b.lastrlw
+		b.cursor = 0
+		b.acc = 0
 	}
 
 	for ; b.cursor < len(b.w); b.cursor++ {
@@ -245,6 +466,12 @@ func (b *Bitmap) Get(pos int64) bool {
 		if l > 0 && pos < acc+l*64 {
 			for j := 1; j <= int(word.l()); j++ {
 				if pos < acc+64 {
+					// a corrupt marker may have more literals than words
+					if b.cursor+j >= len(b.w) {
+						return false
+					}
+
+					b.lastpos = pos
 					w := b.w[b.cursor+j]
 					mask := uint64(1) << (63 - uint64(pos-acc))
 					return w&mask != 0
@@ -263,6 +490,113 @@ func (b *Bitmap) Get(pos int64) bool {
 	return false
 }
 
+// getLast returns the bit at the given position, relative to the base, if
+// it is covered by the last marker. ok is false otherwise.
+func (b *Bitmap) getLast(pos int64) (bit, ok bool) {
+	off := b.lastOffset()
+	if off < 0 || pos < off {
+		return false, false
+	}
+
+	r := rlw(b.w[b.lastrlw])
+	pos -= off
+	if pos < int64(r.k())*64 {
+		return r.b(), true
+	}
+
+	pos -= int64(r.k()) * 64
+	if idx := pos / 64; idx < int64(r.l()) {
+		return b.w[b.lastrlw+1+int(idx)]&(bmask>>uint64(pos%64)) != 0, true
+	}
+	return false, true
+}
+
+// lastOffset returns the position, relative to the base, of the first bit
+// covered by the last marker, or -1 if there is no marker or the words are
+// corrupt.
+func (b *Bitmap) lastOffset() int64 {
+	if b.lastrlw < 0 || b.lastrlw >= len(b.w) || b.lastoff < 0 {
+		return -1
+	}
+
+	if r := rlw(b.w[b.lastrlw]); b.lastrlw+int(r.l())+1 != len(b.w) {
+		return -1
+	}
+	return b.lastoff
+}
+
+// lookup returns the bit at the given position scanning the words from the
+// closest indexed marker, or from the start if there is no index, without
+// using nor modifying the state of the last read.
+func (b *Bitmap) lookup(pos int64) bool {
+	if pos < b.base {
+		return false
+	}
+
+	pos -= b.base
+	if bit, ok := b.getLast(pos); ok {
+		return bit
+	}
+
+	word, acc := b.seekIndex(pos)
+	it := runIterator{w: b.w, next: word, left: math.MaxInt64}
+	it.advance()
+	for !it.done() {
+		acc += it.run * 64
+		if pos < acc {
+			return it.bit
+		}
+
+		if pos < acc+int64(it.lits)*64 {
+			word := it.literal(int((pos - acc) / 64))
+			return word&(bmask>>uint64((pos-acc)%64)) != 0
+		}
+
+		acc += int64(it.lits) * 64
+		it.discard(it.run + int64(it.lits))
+	}
+
+	return false
+}
+
+// seekIndex returns the last indexed marker covering positions not greater
+// than pos, which is relative to the base, along with the position, also
+// relative to the base, of the first bit it covers. Without an index, that is
+// always the first marker.
+func (b *Bitmap) seekIndex(pos int64) (word int, offset int64) {
+	i := sort.Search(len(b.index), func(i int) bool {
+		return b.index[i].offset-b.base > pos
+	})
+	if i == 0 {
+		return 0, 0
+	}
+
+	e := b.index[i-1]
+	return int(e.word), e.offset - b.base
+}
+
+// Freeze makes the bitmap read-only. Frozen bitmaps can be safely read from
+// several goroutines at once, and trying to modify them returns ErrFrozen, or
+// panics for methods that can't return an error. A frozen bitmap can't be
+// unfrozen, but its clones aren't frozen.
+//
+// Freezing also builds an index pointing to every few markers of the words,
+// so Get on a frozen bitmap jumps close to the position with a binary search
+// instead of scanning the words from the start.
+func (b *Bitmap) Freeze() {
+	if !b.frozen {
+		if index := b.skipIndex(DefaultSkipInterval, 0); len(index) > 1 {
+			b.index = index
+		}
+	}
+	b.frozen = true
+}
+
+// Frozen reports whether the bitmap is frozen.
+func (b *Bitmap) Frozen() bool {
+	return b.frozen
+}
+
 // Bits returns the number of uncompressed bits in the bitmap.
 func (b *Bitmap) Bits() uint32 {
 	return uint32(b.n)
@@ -284,11 +618,147 @@ func (b *Bitmap) Bytes() int64 {
 	return int64(len(b.w)*64) / 8
 }
 
+// DeepSizeOf returns the number of bytes of memory retained by the bitmap,
+// including the bitmap itself, all the capacity of its words and of the
+// spare words kept by the in-place operations, and the index built by
+// Freeze. Unlike Bytes, it is meant for memory accounting rather than for
+// the size of the encoded bitmap.
+func (b *Bitmap) DeepSizeOf() int64 {
+	return int64(unsafe.Sizeof(*b)) + int64(cap(b.w)+cap(b.spare))*8 +
+		int64(cap(b.index))*int64(unsafe.Sizeof(skipEntry{}))
+}
+
+// ShrinkToFit reallocates the words of the bitmap so they take no more memory
+// than needed, dropping the spare words kept by the in-place operations, and
+// returns the number of bytes freed. Frozen bitmaps are left untouched.
+func (b *Bitmap) ShrinkToFit() int64 {
+	free := int64(cap(b.w)-len(b.w)+cap(b.spare)) * 8
+	if free == 0 || b.frozen {
+		return 0
+	}
+
+	b.spare = nil
+	if cap(b.w) > len(b.w) {
+		var w []uint64
+		if len(b.w) > 0 {
+			w = make([]uint64, len(b.w))
+			copy(w, b.w)
+		}
+		b.w = w
+	}
+
+	return free
+}
+
+// Clone returns a copy of the bitmap that does not share any memory with it,
+// so either of them can be modified without affecting the other. The copy is
+// never frozen.
+func (b *Bitmap) Clone() *Bitmap {
+	var w []uint64
+	if len(b.w) > 0 {
+		w = make([]uint64, len(b.w))
+		copy(w, b.w)
+	}
+	return &Bitmap{
+		n: b.n, base: b.base, w: w,
+		lastrlw: b.lastrlw, prevrlw: b.prevrlw, lastoff: b.lastoff,
+		card: b.card, hasCard: b.hasCard,
+	}
+}
+
+// CopyTo makes dst a copy of the bitmap, like Clone does, but reusing the
+// memory of the words of dst when it has room for them. dst must not be
+// frozen.
+func (b *Bitmap) CopyTo(dst *Bitmap) {
+	if dst == b {
+		return
+	}
+
+	dst.clear()
+	dst.n, dst.base, dst.w = b.n, b.base, append(dst.w, b.w...)
+	dst.lastrlw, dst.prevrlw, dst.lastoff = b.lastrlw, b.prevrlw, b.lastoff
+	dst.card, dst.hasCard = b.card, b.hasCard
+}
+
+// replace makes the bitmap take the contents of other, which must not be
+// used afterwards.
+func (b *Bitmap) replace(other *Bitmap) {
+	b.clear()
+	b.n, b.base, b.w = other.n, other.base, other.w
+	b.lastrlw, b.prevrlw, b.lastoff = other.lastrlw, other.prevrlw, other.lastoff
+	b.card, b.hasCard = other.card, other.hasCard
+}
+
 // Reset clears the bitmap and sets everything to unused empty zeroes.
+// Frozen bitmaps can't be reset, and Reset panics with ErrFrozen on them.
 func (b *Bitmap) Reset() {
-	b.n = 0
+	b.clear()
 	b.w = nil
+}
+
+// Truncate drops all the bits at or beyond n, leaving the bitmap with n bits
+// if it had more. Runs crossing n are cut short and the bits beyond n in the
+// last word are cleared, without decoding the rest of the words.
+func (b *Bitmap) Truncate(n int64) error {
+	if b.frozen {
+		return ErrFrozen
+	}
+
+	n = maxInt64(n, 0)
+	if n >= b.n {
+		return nil
+	}
+
+	if n <= b.base {
+		b.clear()
+		b.base = n / 64 * 64
+		b.n = b.base
+		b.extend(n)
+		return nil
+	}
+
+	words := wordsFor(n) - b.base/64
+	for i := 0; i < len(b.w); {
+		r := rlw(b.w[i])
+		k, l := int64(r.k()), int64(r.l())
+		if words <= k+l {
+			k = minInt64(k, words)
+			l = words - k
+			r.setk(uint32(k))
+			r.setl(uint32(l))
+			b.w[i] = uint64(r)
+			b.w = b.w[:i+1+int(l)]
+			b.setLast(i)
+			break
+		}
+
+		words -= k + l
+		i += int(l) + 1
+	}
+
+	b.n = n
+	b.cursor, b.lastpos, b.acc = 0, 0, 0
+	b.hasCard = false
+	b.trimTail()
+	return nil
+}
+
+// clear is like Reset, but keeps the memory of the words for reuse.
+func (b *Bitmap) clear() {
+	if b.frozen {
+		panic(ErrFrozen)
+	}
+
+	b.n = b.base
+	b.w = b.w[:0]
 	b.lastrlw = -1
+	b.prevrlw = -1
+	b.lastoff = 0
+	b.cursor = 0
+	b.lastpos = 0
+	b.acc = 0
+	b.card = 0
+	b.hasCard = false
 }
 
 // setbit sets to 1 the bit in the given idx.
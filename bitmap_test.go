@@ -1,14 +1,19 @@
 package ewah
 
 import (
+	"bufio"
 	"bytes"
 	"encoding/binary"
+	"errors"
 	"fmt"
+	"io"
 	"math"
+	"math/rand"
 	"os"
 	"strconv"
 	"strings"
 	"testing"
+	"unsafe"
 
 	"github.com/stretchr/testify/require"
 )
@@ -27,6 +32,37 @@ func TestBitmapReadWrite(t *testing.T) {
 	require.Equal(b, b2)
 }
 
+func TestBitmapWriteWithOptions(t *testing.T) {
+	b := newBitmap()
+
+	testCases := []struct {
+		opts WriteOptions
+		pad  int64
+	}{
+		{WriteOptions{Order: binary.BigEndian}, 0},
+		{WriteOptions{Order: binary.BigEndian, Align: 4}, 0},
+		{WriteOptions{Order: binary.BigEndian, Align: 8}, 4},
+		{WriteOptions{Order: binary.LittleEndian, Align: 8, Offset: 4}, 0},
+		{WriteOptions{Order: binary.LittleEndian, Align: 8, Offset: 3}, 1},
+		{WriteOptions{Order: binary.LittleEndian, Align: 16, Offset: 10}, 10},
+	}
+
+	for _, tt := range testCases {
+		require := require.New(t)
+
+		buf := bytes.NewBuffer(nil)
+		n, err := b.WriteWithOptions(buf, tt.opts)
+		require.NoError(err)
+		require.Equal(int64(60)+tt.pad, n)
+		require.Equal(int(n), buf.Len())
+		require.Equal(make([]byte, tt.pad), buf.Bytes()[60:])
+
+		b2, err := FromBytes(buf.Bytes(), tt.opts.Order)
+		require.NoError(err)
+		require.Equal(b, b2)
+	}
+}
+
 func TestBitmapGet(t *testing.T) {
 	require := require.New(t)
 
@@ -80,6 +116,83 @@ func TestBitmapGet(t *testing.T) {
 	}
 }
 
+func TestBitmapGetBackwards(t *testing.T) {
+	require := require.New(t)
+
+	b := fromPositions(10, 582, 700)
+	require.True(b.Get(582))
+	require.True(b.Get(10))
+	require.True(b.Get(700))
+	require.True(b.Get(582))
+	require.False(b.Get(11))
+}
+
+func TestBitmapGetFrozenIndex(t *testing.T) {
+	require := require.New(t)
+	r := rand.New(rand.NewSource(1))
+
+	for _, base := range []int64{0, 64 * 100} {
+		ps := randomPositions(r, 200000, 0.01)
+		for i := range ps {
+			ps[i] += base
+		}
+
+		b := fromPositions(ps...)
+		frozen := b.Clone()
+		frozen.Freeze()
+		require.True(len(frozen.index) > 1)
+
+		for i := 0; i < 2000; i++ {
+			pos := int64(r.Intn(210000))
+			require.Equal(b.Get(pos), frozen.Get(pos), "position %d", pos)
+		}
+
+		for _, pos := range ps {
+			require.True(frozen.Get(pos))
+		}
+	}
+
+	b := fromPositions(1, 2, 3)
+	b.Freeze()
+	require.Nil(b.index)
+	require.True(b.Get(2))
+}
+
+func TestBitmapGetLast(t *testing.T) {
+	require := require.New(t)
+
+	b := fromPositions(3, 700, 5000, 5001, 5070, 6400*64)
+	require.True(b.Get(3))
+	for _, frozen := range []bool{false, true} {
+		if frozen {
+			b.Freeze()
+		}
+
+		// positions in the last marker don't move the cursor
+		cursor := b.cursor
+		require.True(b.Get(6400 * 64))
+		require.False(b.Get(6400*64 - 1))
+		require.Equal(cursor, b.cursor)
+
+		require.True(b.Get(5070))
+		require.True(b.Get(700))
+		require.False(b.Get(5069))
+	}
+
+	// words that cover fewer bits than the bitmap has
+	b, err := FromBytes([]byte{
+		0, 0, 0x03, 0xe8, 0, 0, 0, 0x02,
+		0, 0, 0, 0, 0x80, 0, 0, 0x01,
+		0x80, 0, 0, 0, 0, 0, 0, 0,
+		0, 0, 0, 0,
+	}, binary.BigEndian)
+	require.NoError(err)
+	require.Equal(int64(1000), b.n)
+	require.True(b.Get(64))
+	require.False(b.Get(500))
+	require.False(b.Get(65))
+}
+
 func TestBitmapSet(t *testing.T) {
 	require := require.New(t)
 	b := New()
@@ -104,6 +217,74 @@ func TestBitmapSet(t *testing.T) {
 	require.Equal(newBitmap(), b)
 }
 
+func TestBitmapClone(t *testing.T) {
+	require := require.New(t)
+
+	b := newBitmap()
+	clone := b.Clone()
+	require.Equal(b, clone)
+
+	ps := positions(b)
+	require.NoError(clone.Set(20 * 64))
+	require.NoError(clone.Clear(ps[0]))
+	require.Equal(newBitmap().w, b.w)
+	require.Equal(ps, positions(b))
+	require.Equal(append(ps[1:], 20*64), positions(clone))
+
+	require.Equal(New(), New().Clone())
+}
+
+func TestBitmapCopyTo(t *testing.T) {
+	require := require.New(t)
+
+	b := newBitmap()
+	dst := fromPositions(randomPositions(rand.New(rand.NewSource(1)), 10000, 0.5)...)
+	words := &dst.w[0]
+
+	b.CopyTo(dst)
+	require.Equal(b, dst)
+	require.Same(words, &dst.w[0])
+
+	require.NoError(dst.Set(20 * 64))
+	require.Equal(newBitmap(), b)
+
+	allocs := testing.AllocsPerRun(10, func() {
+		b.CopyTo(dst)
+	})
+	require.Zero(allocs)
+
+	b.CopyTo(b)
+	require.Equal(newBitmap(), b)
+
+	dst.Freeze()
+	require.Panics(func() { b.CopyTo(dst) })
+}
+
+func TestBitmapSetDuplicates(t *testing.T) {
+	require := require.New(t)
+	r := rand.New(rand.NewSource(1))
+
+	ps := randomPositions(r, 10000, 0.3)
+	b := New()
+	for _, p := range ps {
+		require.NoError(b.Set(p))
+		require.NoError(b.Set(p))
+	}
+	require.NoError(b.Set(ps[0]))
+	require.Equal(fromPositions(ps...).w, b.w)
+	require.Equal(ps[len(ps)-1]+1, b.n)
+
+	unset := ps[0] + 1
+	for b.Get(unset) {
+		unset++
+	}
+	require.Equal(ErrInvalidBitSet, b.Set(unset))
+
+	base := NewWithBase(6400)
+	require.NoError(base.Set(6500))
+	require.Equal(ErrInvalidBitSet, base.Set(10))
+}
+
 func TestBitmapSetOverflowL(t *testing.T) {
 	if testing.Short() {
 		t.Skip("not running this on short mode")
@@ -185,6 +366,357 @@ func TestBitmapSetAllOnesPrevRlw(t *testing.T) {
 	require.Equal(uint64(newRlw(true, 2, 0)), b.w[0])
 }
 
+func TestBitmapSetAfterOnesRun(t *testing.T) {
+	require := require.New(t)
+
+	b := New()
+	for i := int64(0); i < 64; i++ {
+		require.NoError(b.Set(i))
+	}
+	require.NoError(b.Set(200))
+
+	require.Equal([]uint64{
+		uint64(newRlw(true, 1, 0)),
+		uint64(newRlw(false, 2, 1)),
+		uint64(1) << 55,
+	}, b.w)
+	require.True(b.Get(63))
+	require.False(b.Get(100))
+	require.True(b.Get(200))
+}
+
+func TestBitmapSetInZeroRun(t *testing.T) {
+	require := require.New(t)
+
+	b := New()
+	b.appendClean(false, 2)
+	b.lastrlw = 0
+	b.n = 100
+
+	require.NoError(b.Set(120))
+	require.Equal([]uint64{
+		uint64(newRlw(false, 1, 1)),
+		uint64(1) << (63 - 56),
+	}, b.w)
+	require.Equal([]int64{120}, positions(b))
+}
+
+func TestBitmapFoldLast(t *testing.T) {
+	require := require.New(t)
+
+	b := fromPositions(1, 130)
+	require.Equal([]uint64{
+		uint64(newRlw(false, 0, 1)),
+		uint64(1) << 62,
+		uint64(newRlw(false, 1, 1)),
+		uint64(1) << 61,
+	}, b.w)
+
+	// a literal that becomes all zeroes is merged into the previous run
+	b.w[3] = 0
+	b.foldLast()
+	require.Equal([]uint64{
+		uint64(newRlw(false, 0, 1)),
+		uint64(1) << 62,
+		uint64(newRlw(false, 2, 0)),
+	}, b.w)
+	require.Equal([]int64{1}, positions(b))
+
+	// only the last word is folded
+	b.w[1] = 0
+	b.foldLast()
+	require.Len(b.w, 3)
+
+	b = fromPositions(1)
+	b.w[1] = allones
+	b.foldLast()
+	require.Equal([]uint64{uint64(newRlw(true, 1, 0))}, b.w)
+	require.Equal(0, b.lastrlw)
+}
+
+func TestBitmapMergeRuns(t *testing.T) {
+	require := require.New(t)
+
+	b := New()
+	for i := int64(0); i < 192; i++ {
+		require.NoError(b.Set(i))
+	}
+
+	// the last word is the tail of a run of zeroes right after a run of ones
+	b.appendClean(false, 1)
+	b.n = 193
+	require.Equal(0, b.prevrlw)
+
+	require.NoError(b.Set(193))
+	require.Equal([]uint64{uint64(newRlw(true, 3, 1)), uint64(1) << 62}, b.w)
+	require.Equal(0, b.lastrlw)
+	require.Equal(-1, b.prevrlw)
+
+	// a literal under its own marker that becomes all ones is merged into
+	// the previous run
+	b = New()
+	for i := int64(0); i < 192; i++ {
+		require.NoError(b.Set(i))
+	}
+	b.w = append(b.w, uint64(newRlw(false, 0, 1)), allones&^1)
+	b.lastrlw, b.prevrlw = 1, 0
+	b.n = 255
+
+	require.NoError(b.Set(255))
+	require.Equal([]uint64{uint64(newRlw(true, 4, 0))}, b.w)
+	require.Equal(0, b.lastrlw)
+	require.Len(positions(b), 256)
+}
+
+func TestBitmapTruncate(t *testing.T) {
+	require := require.New(t)
+	r := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 50; i++ {
+		ps := randomPositions(r, 5000, r.Float64())
+		b := fromPositions(ps...)
+		n := int64(r.Intn(5500))
+
+		var expected []int64
+		for _, p := range ps {
+			if p < n {
+				expected = append(expected, p)
+			}
+		}
+
+		require.NoError(b.Truncate(n))
+		require.Equal(minInt64(n, int64(ps[len(ps)-1]+1)), b.n)
+		require.Equal(expected, positions(b))
+		require.Equal(or(fromPositions(expected...), zeroes(b.n)).w, b.w)
+		require.Equal(countWords(b), wordsFor(b.n))
+
+		require.NoError(b.Set(b.n + 3))
+		require.True(b.Get(b.n - 1))
+	}
+
+	b := New()
+	for i := int64(0); i < 64*4; i++ {
+		require.NoError(b.Set(i))
+	}
+	require.NoError(b.Truncate(64*2 + 3))
+	require.Equal([]uint64{uint64(newRlw(true, 2, 1)), uint64(7) << 61}, b.w)
+
+	b = NewWithBase(640)
+	require.NoError(b.Set(700))
+	require.NoError(b.Truncate(100))
+	require.Equal(int64(100), b.n)
+	require.Equal(int64(64), b.Base())
+	require.Equal([]uint64{uint64(newRlw(false, 1, 0))}, b.w)
+	require.NoError(b.Set(120))
+	require.Equal([]int64{120}, positions(b))
+
+	b.Freeze()
+	require.Equal(ErrFrozen, b.Truncate(0))
+}
+
+func TestBitmapResetFrozen(t *testing.T) {
+	require := require.New(t)
+
+	b := newBitmap()
+	b.Reset()
+	require.Equal(New(), b)
+
+	b = newBitmap()
+	b.Freeze()
+	require.PanicsWithValue(ErrFrozen, func() { b.Reset() })
+	require.Equal(positions(newBitmap()), positions(b))
+}
+
+func TestBitmapShrinkToFit(t *testing.T) {
+	require := require.New(t)
+
+	b := newBitmap()
+	b.w = append(make([]uint64, 0, 100), b.w...)
+
+	require.Equal(int64(94*8), b.ShrinkToFit())
+	require.Equal(len(b.w), cap(b.w))
+	require.Equal(newBitmap(), b)
+	require.Equal(int64(0), b.ShrinkToFit())
+
+	b = New()
+	b.w = make([]uint64, 0, 10)
+	require.Equal(int64(80), b.ShrinkToFit())
+	require.Nil(b.w)
+}
+
+func TestBitmapDeepSizeOf(t *testing.T) {
+	require := require.New(t)
+
+	b := newBitmap()
+	base := int64(unsafe.Sizeof(*b))
+	require.Equal(base+int64(cap(b.w))*8, b.DeepSizeOf())
+
+	b.w = append(make([]uint64, 0, 100), b.w...)
+	require.Equal(base+800, b.DeepSizeOf())
+	require.Equal(int64(48), b.Bytes())
+
+	b.ShrinkToFit()
+	require.Equal(base+48, b.DeepSizeOf())
+
+	// the index built by Freeze is counted too
+	r := rand.New(rand.NewSource(1))
+	b = fromPositions(randomPositions(r, 200000, 0.01)...)
+	size := b.DeepSizeOf()
+	b.Freeze()
+	require.True(len(b.index) > 1)
+	require.Equal(size+int64(cap(b.index))*int64(unsafe.Sizeof(skipEntry{})), b.DeepSizeOf())
+}
+
+func TestBitmapBase(t *testing.T) {
+	require := require.New(t)
+
+	base := int64(3000000000)
+	b := NewWithBase(base + 5)
+	require.Equal(base/64*64, b.Base())
+	require.Equal(ErrInvalidBitSet, b.Set(base-100))
+
+	ps := []int64{base + 10, base + 11, base + 200, base + 1000}
+	for _, p := range ps {
+		require.NoError(b.Set(p))
+	}
+
+	require.Equal(int64(base+1001), b.n)
+	require.Equal(int64(6*8), b.Bytes())
+	require.False(b.Get(0))
+	require.False(b.Get(base - 1))
+	for _, p := range ps {
+		require.True(b.Get(p))
+	}
+	require.False(b.Get(base + 12))
+
+	require.Equal(ps, iterate(b.Iterator()))
+	require.Equal(ps[1:3], iterate(b.IteratorRange(base+11, base+201)))
+
+	buf := bytes.NewBuffer(nil)
+	_, err := b.Write(buf, binary.BigEndian)
+	require.NoError(err)
+
+	b2, err := FromBytes(buf.Bytes(), binary.BigEndian)
+	require.NoError(err)
+	require.Equal(int64(0), b2.Base())
+	require.Equal(ps, iterate(b2.Iterator()))
+	require.NoError(b2.Set(base + 2000))
+
+	other := fromPositions(5, base+11, base+2000)
+	require.Equal([]int64{base + 11}, iterate(and(b, other).Iterator()))
+	require.Equal([]int64{5, base + 10, base + 11, base + 200, base + 1000, base + 2000}, iterate(or(other, b).Iterator()))
+
+	result := or(b, NewWithBase(base+6400))
+	require.Equal(b.Base(), result.Base())
+	require.Equal(ps, iterate(result.Iterator()))
+	require.Equal(int64(base+6400), result.n)
+
+	indexed := bytes.NewBuffer(nil)
+	_, err = b.WriteIndexed(indexed, binary.BigEndian, 1)
+	require.NoError(err)
+
+	l, err := NewLazyReader(bytes.NewReader(indexed.Bytes()), int64(indexed.Len()), binary.BigEndian)
+	require.NoError(err)
+	for _, p := range []int64{0, base + 9, base + 10, base + 200, base + 1000} {
+		v, err := l.Get(p)
+		require.NoError(err)
+		require.Equal(b.Get(p), v)
+	}
+}
+
+func TestFromReaderMalicious(t *testing.T) {
+	require := require.New(t)
+
+	// a few bytes claiming the maximum number of words
+	huge := []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0, 0, 0, 0}
+	_, err := FromBytes(huge, binary.BigEndian)
+	require.True(errors.Is(err, io.ErrUnexpectedEOF))
+
+	_, err = FromReader(&io.LimitedReader{R: bytes.NewReader(huge), N: 100}, binary.BigEndian)
+	require.True(errors.Is(err, io.ErrUnexpectedEOF))
+
+	// readers that don't know how many bytes they have left
+	_, err = FromReader(bufio.NewReader(bytes.NewReader(huge)), binary.BigEndian)
+	require.Error(err)
+
+	_, err = FromReaderWithOptions(bytes.NewReader(huge), ReadOptions{Order: binary.BigEndian, MaxWords: 1000})
+	require.True(errors.Is(err, ErrTooManyWords))
+
+	b := fromPositions(1, 100, 700)
+	var buf bytes.Buffer
+	_, err = b.Write(&buf, binary.BigEndian)
+	require.NoError(err)
+	data := buf.Bytes()
+
+	_, err = FromReaderWithOptions(bytes.NewReader(data), ReadOptions{Order: binary.BigEndian, MaxWords: 2})
+	require.True(errors.Is(err, ErrTooManyWords))
+
+	result, err := FromReaderWithOptions(bytes.NewReader(data), ReadOptions{Order: binary.BigEndian, MaxWords: len(b.w)})
+	require.NoError(err)
+	require.Equal(b.w, result.w)
+
+	// positions of the current RLW that are out of range or not a marker
+	for _, last := range []uint32{uint32(len(b.w)), 1, 1 << 30} {
+		corrupt := append([]byte(nil), data...)
+		binary.BigEndian.PutUint32(corrupt[len(corrupt)-4:], last)
+		_, err := FromBytes(corrupt, binary.BigEndian)
+		var cerr *CorruptError
+		require.True(errors.As(err, &cerr), "position %d", last)
+	}
+
+	// bitmaps without words have no current RLW
+	b, err = FromBytes([]byte{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 5}, binary.BigEndian)
+	require.NoError(err)
+	require.Equal(-1, b.lastrlw)
+	require.NoError(b.Set(3))
+}
+
+func TestBitmapCorrupt(t *testing.T) {
+	require := require.New(t)
+
+	corrupt := []*Bitmap{
+		// marker with more literals than words
+		{n: 640, w: []uint64{uint64(newRlw(false, 1, 5)), 0xff, 0xf0}, lastrlw: 0},
+		// last marker out of range
+		{n: 192, w: []uint64{uint64(newRlw(true, 3, 0))}, lastrlw: 7},
+		// last marker is not the last one
+		{n: 256, w: []uint64{uint64(newRlw(true, 1, 1)), 0xf, uint64(newRlw(false, 2, 0))}, lastrlw: 0},
+		// bits but no words
+		{n: 100, lastrlw: -1},
+	}
+
+	for i, b := range corrupt {
+		for pos := int64(0); pos < b.n+10; pos++ {
+			b.Get(pos)
+			b.Explain(pos)
+		}
+
+		var err *CorruptError
+		require.True(errors.As(b.Validate(), &err), "bitmap %d", i)
+		require.True(errors.As(b.Set(b.n), &err), "bitmap %d", i)
+
+		other := fromPositions(1, 70, 700)
+		require.NotPanics(func() {
+			for it := b.Iterator(); it.HasNext(); {
+				it.Next()
+			}
+			for it := b.IteratorRange(64, b.n); it.HasNext(); {
+				it.Next()
+			}
+			b.Cardinality()
+			and(b, other)
+			or(other, b)
+			not(b, 1000)
+			OrTo(New(), b.Complement(1000), other)
+		}, "bitmap %d", i)
+	}
+
+	b, err := FromBytes([]byte{0, 0, 0, 0, 0, 0, 0, 0, 0xff, 0xff, 0xff, 0xff}, binary.BigEndian)
+	require.NoError(err)
+	require.NoError(b.Set(5))
+	require.True(b.Get(5))
+}
+
 func TestRlwSetl(t *testing.T) {
 	require := require.New(t)
 
@@ -299,9 +831,52 @@ func newBitmap() *Bitmap {
 	}
 	b.n = 10 * 64
 	b.lastrlw = 5
+	b.prevrlw = 3
+	b.lastoff = 9 * 64
+	return b
+}
+
+// fromPositions creates a bitmap with the given sorted positions set.
+func fromPositions(positions ...int64) *Bitmap {
+	b := New()
+	for _, p := range positions {
+		if err := b.Set(p); err != nil {
+			panic(err)
+		}
+	}
 	return b
 }
 
+// positions returns all the set positions in the bitmap, checking them one
+// by one with Get.
+func positions(b *Bitmap) []int64 {
+	var result []int64
+	for i := int64(0); i < b.n; i++ {
+		if b.Get(i) {
+			result = append(result, i)
+		}
+	}
+	return result
+}
+
+// randomPositions returns sorted random positions in [0, n), each of them
+// set with the given probability. Positions tend to cluster in runs so the
+// resulting bitmaps have both clean and literal words.
+func randomPositions(r *rand.Rand, n int64, density float64) []int64 {
+	var result []int64
+	for i := int64(0); i < n; {
+		run := int64(r.Intn(300)) + 1
+		mode := r.Intn(4)
+		for j := i; j < i+run && j < n; j++ {
+			if mode == 0 || (mode == 1 && r.Float64() < density) {
+				result = append(result, j)
+			}
+		}
+		i += run
+	}
+	return result
+}
+
 func newBigBitmap() (*Bitmap, error) {
 	b := New()
 
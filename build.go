@@ -0,0 +1,21 @@
+package ewah
+
+// Build creates a bitmap of n bits where the bit at every position is the
+// result of calling pred with it. pred is called once for every position in
+// ascending order, and the bits are encoded as they are computed, so long
+// stretches of the same result become clean runs without ever holding the
+// uncompressed bitmap in memory.
+func Build(n int64, pred func(pos int64) bool) *Bitmap {
+	s := newStreamBuilder()
+	for start := int64(0); start < n; start += 64 {
+		count := minInt64(64, n-start)
+		var word uint64
+		for i := int64(0); i < count; i++ {
+			if pred(start + i) {
+				word |= bmask >> uint64(i)
+			}
+		}
+		s.addBits(word, int(count))
+	}
+	return s.bitmap()
+}
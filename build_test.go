@@ -0,0 +1,36 @@
+package ewah
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuild(t *testing.T) {
+	require := require.New(t)
+
+	var calls []int64
+	b := Build(1000, func(pos int64) bool {
+		calls = append(calls, pos)
+		return pos%3 == 0 || pos >= 200 && pos < 900
+	})
+
+	require.Len(calls, 1000)
+	for i, pos := range calls {
+		require.Equal(int64(i), pos)
+	}
+
+	expected := New()
+	for pos := int64(0); pos < 1000; pos++ {
+		if pos%3 == 0 || pos >= 200 && pos < 900 {
+			require.NoError(expected.Set(pos))
+		}
+	}
+	require.Equal(int64(1000), b.n)
+	require.Equal(expected.w, b.w)
+
+	ones := Build(64*10, func(int64) bool { return true })
+	require.Equal([]uint64{uint64(newRlw(true, 10, 0))}, ones.w)
+
+	require.Equal(int64(0), Build(0, nil).n)
+}
@@ -0,0 +1,60 @@
+package ewah
+
+// Builder builds a bitmap from positions and intervals given in ascending
+// order. Unlike Bitmap.Set, adding bits never fails: they are encoded right
+// away, with runs of ones added as a whole, and any error is only reported
+// by Build. Positions, and intervals starting at them, may repeat bits that
+// are already added, as long as they do not go back past the last unset bit.
+type Builder struct {
+	s *streamBuilder
+	// ones is the position of the first bit of the run of ones at the end of
+	// the bits added so far, which is the number of bits if there is none.
+	ones int64
+	err  error
+}
+
+// NewBuilder creates an empty Builder.
+func NewBuilder() *Builder {
+	return &Builder{s: newStreamBuilder()}
+}
+
+// Add sets the bit at the given position.
+func (b *Builder) Add(pos int64) {
+	b.AddRange(pos, pos+1)
+}
+
+// AddRange sets all the bits in the interval [start, end).
+func (b *Builder) AddRange(start, end int64) {
+	if start >= end || b.err != nil {
+		return
+	}
+
+	if start < b.ones {
+		b.err = ErrInvalidBitSet
+		return
+	}
+
+	if start > b.s.n {
+		b.s.addRun(false, start-b.s.n)
+		b.ones = start
+	}
+
+	if end > b.s.n {
+		b.s.addRun(true, end-b.s.n)
+	}
+}
+
+// Build returns the built bitmap, which has as many bits as needed to hold
+// the last bit added, or ErrInvalidBitSet if any bit was added out of order.
+// The builder is empty again afterwards.
+func (b *Builder) Build() (*Bitmap, error) {
+	s, err := b.s, b.err
+	*b = Builder{s: newStreamBuilder()}
+	if err != nil {
+		return nil, err
+	}
+
+	out := s.bitmap()
+	out.assertInvariants()
+	return out, nil
+}
@@ -0,0 +1,60 @@
+package ewah
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuilder(t *testing.T) {
+	require := require.New(t)
+	r := rand.New(rand.NewSource(1))
+
+	builder := NewBuilder()
+	for i := 0; i < 30; i++ {
+		expected := New()
+		var pos int64
+		for j := 0; j < 100; j++ {
+			pos += int64(r.Intn(500))
+			if r.Intn(2) == 0 {
+				builder.Add(pos)
+				builder.Add(pos)
+				require.NoError(expected.Set(pos))
+				continue
+			}
+
+			end := pos + int64(r.Intn(1000))
+			builder.AddRange(pos, end)
+			require.NoError(expected.SetRange(pos, end))
+			if end > pos {
+				pos = end - 1
+			}
+		}
+
+		b, err := builder.Build()
+		require.NoError(err)
+		require.Equal(positions(expected), positions(b))
+		require.Equal(expected.w, b.w)
+		require.Equal(expected.n, b.n)
+	}
+
+	builder.AddRange(10, 100)
+	builder.Add(50)
+	builder.AddRange(60, 200)
+	builder.Add(300)
+	b, err := builder.Build()
+	require.NoError(err)
+	require.Equal(int64(191), b.Cardinality())
+
+	builder.Add(10)
+	builder.AddRange(20, 30)
+	builder.Add(15)
+	builder.Add(40)
+	_, err = builder.Build()
+	require.Equal(ErrInvalidBitSet, err)
+
+	b, err = builder.Build()
+	require.NoError(err)
+	require.Equal(New(), b)
+}
@@ -0,0 +1,153 @@
+package ewah
+
+import "math/bits"
+
+// Cardinality returns the number of set bits. Runs of ones are counted as a
+// whole and literal words with a population count, so it does not look at
+// every bit. The count is cached and kept up to date as bits are set and by
+// the logical operations, so it is only computed again after operations
+// that can't update it cheaply, such as Truncate. Frozen bitmaps only use
+// the count cached before freezing them.
+func (b *Bitmap) Cardinality() int64 {
+	if b.hasCard {
+		return b.card
+	}
+
+	count := b.cardinality()
+	if !b.frozen {
+		b.card, b.hasCard = count, true
+	}
+	return count
+}
+
+// CountRange returns the number of set bits in the interval [start, end).
+// Runs are counted with arithmetic and literal words with a population
+// count, masking the ones that are only partly in the interval, and the
+// words after end are not looked at.
+func (b *Bitmap) CountRange(start, end int64) int64 {
+	start = maxInt64(start, 0)
+	var count, offset int64
+	it := b.runs()
+	for !it.done() && offset < end {
+		if it.run > 0 {
+			next := offset + it.run*64
+			if it.bit {
+				count += maxInt64(minInt64(next, end)-maxInt64(offset, start), 0)
+			}
+
+			offset = next
+			it.discard(it.run)
+			continue
+		}
+
+		if offset+64 > start {
+			word := it.literal(0)
+			if start > offset {
+				word &= allones >> uint(start-offset)
+			}
+			if end < offset+64 {
+				word &= ^(allones >> uint(end-offset))
+			}
+			count += int64(bits.OnesCount64(word))
+		}
+
+		offset += 64
+		it.discard(1)
+	}
+	return count
+}
+
+// AndCardinality returns the number of set bits the bitmap has in common
+// with other, counting them while both are walked in lockstep, without
+// building their intersection.
+func (b *Bitmap) AndCardinality(other *Bitmap) int64 {
+	return mergeCount(b, other, opAnd)
+}
+
+// OrCardinality returns the number of bits set in the bitmap, in other or in
+// both, counting them while both are walked in lockstep, without building
+// their union.
+func (b *Bitmap) OrCardinality(other *Bitmap) int64 {
+	return mergeCount(b, other, opOr)
+}
+
+// mergeCount returns the number of set bits in the result of combining a and
+// b with op, without writing the result anywhere.
+func mergeCount(a, b Operand, op func(x, y uint64) uint64) int64 {
+	var count int64
+	mergeWords(a, b, op, func(word uint64, k int64) bool {
+		count += int64(bits.OnesCount64(word)) * k
+		return true
+	})
+	return count
+}
+
+// AndCardinalities returns, for each of the others, the number of set bits
+// it has in common with query. The words of query with set bits are located
+// only once and shared by all the counts, which makes it cheaper than
+// intersecting query with every bitmap on its own.
+func AndCardinalities(query *Bitmap, others []*Bitmap) []int64 {
+	segments := query.setSegments()
+	result := make([]int64, len(others))
+	for i, o := range others {
+		result[i] = o.andCountSegments(segments, query.w)
+	}
+	return result
+}
+
+// andCountSegments returns the number of set bits b has in common with the
+// bitmap the given segments come from, whose words are w.
+func (b *Bitmap) andCountSegments(segments []setSegment, w []uint64) int64 {
+	var count, offset int64
+	j := 0
+	it := b.runs()
+	for !it.done() && j < len(segments) {
+		for j < len(segments) && segments[j].start+segments[j].count <= offset {
+			j++
+		}
+
+		if it.run > 0 {
+			end := offset + it.run
+			for i := j; it.bit && i < len(segments) && segments[i].start < end; i++ {
+				count += segments[i].countRange(w, maxInt64(offset, segments[i].start), end)
+			}
+
+			offset = end
+			it.discard(it.run)
+			continue
+		}
+
+		if j < len(segments) && segments[j].start <= offset {
+			count += int64(bits.OnesCount64(it.literal(0) & segments[j].word(w, offset)))
+		}
+
+		offset++
+		it.discard(1)
+	}
+
+	return count
+}
+
+// word returns the word of the segment at the given index of the
+// uncompressed bitmap, which must be in the segment.
+func (s setSegment) word(w []uint64, idx int64) uint64 {
+	if s.lit < 0 {
+		return allones
+	}
+	return w[s.lit+int(idx-s.start)]
+}
+
+// countRange returns the number of set bits of the segment in the words from
+// index from, which must be in the segment, up to index to.
+func (s setSegment) countRange(w []uint64, from, to int64) int64 {
+	to = minInt64(to, s.start+s.count)
+	if s.lit < 0 {
+		return (to - from) * 64
+	}
+
+	var count int64
+	for _, word := range w[s.lit+int(from-s.start) : s.lit+int(to-s.start)] {
+		count += int64(bits.OnesCount64(word))
+	}
+	return count
+}
@@ -0,0 +1,152 @@
+package ewah
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCardinality(t *testing.T) {
+	require := require.New(t)
+	r := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 20; i++ {
+		ps := randomPositions(r, int64(r.Intn(20000)), r.Float64())
+		require.Equal(int64(len(ps)), fromPositions(ps...).Cardinality())
+	}
+
+	require.Equal(int64(0), New().Cardinality())
+	require.Equal(int64(0), New().Not().Cardinality())
+	require.Equal(int64(1000), zeroes(1000).Not().Cardinality())
+}
+
+func TestCountRange(t *testing.T) {
+	require := require.New(t)
+	r := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 30; i++ {
+		n := int64(r.Intn(10000))
+		ps := randomPositions(r, n, r.Float64())
+		b := fromPositions(ps...)
+
+		for j := 0; j < 20; j++ {
+			start := int64(r.Intn(int(n)+200)) - 100
+			end := start + int64(r.Intn(3000))
+
+			var expected int64
+			for _, p := range ps {
+				if p >= start && p < end {
+					expected++
+				}
+			}
+			require.Equal(expected, b.CountRange(start, end), "[%d, %d)", start, end)
+		}
+		require.Equal(int64(len(ps)), b.CountRange(0, n))
+	}
+
+	ones := zeroes(1000).Not()
+	require.Equal(int64(100), ones.CountRange(10, 110))
+	require.Equal(int64(0), ones.CountRange(500, 100))
+	require.Equal(int64(0), New().CountRange(0, 100))
+}
+
+func TestMergeCardinality(t *testing.T) {
+	require := require.New(t)
+	r := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 30; i++ {
+		a := NewWithBase(int64(r.Intn(5000)))
+		for _, p := range randomPositions(r, int64(r.Intn(10000)), r.Float64()) {
+			if p >= a.Base() {
+				require.NoError(a.Set(p))
+			}
+		}
+		b := fromPositions(randomPositions(r, int64(r.Intn(10000)), r.Float64())...)
+
+		require.Equal(and(a, b).cardinality(), a.AndCardinality(b))
+		require.Equal(and(a, b).cardinality(), b.AndCardinality(a))
+		require.Equal(or(a, b).cardinality(), a.OrCardinality(b))
+		require.Equal(or(a, b).cardinality(), b.OrCardinality(a))
+	}
+
+	require.Equal(int64(0), New().AndCardinality(fromPositions(1, 2)))
+	require.Equal(int64(2), New().OrCardinality(fromPositions(1, 2)))
+}
+
+func TestAndCardinalities(t *testing.T) {
+	require := require.New(t)
+	r := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 20; i++ {
+		query := fromPositions(randomPositions(r, 8000, r.Float64())...)
+		var others []*Bitmap
+		var expected []int64
+		for j := 0; j < 10; j++ {
+			o := New()
+			if j%3 == 0 {
+				o = NewWithBase(int64(r.Intn(8000)))
+			}
+			for _, p := range randomPositions(r, 10000, r.Float64()) {
+				if p >= o.Base() {
+					require.NoError(o.Set(p))
+				}
+			}
+
+			others = append(others, o)
+			expected = append(expected, and(query, o).cardinality())
+		}
+
+		require.Equal(expected, AndCardinalities(query, others))
+	}
+
+	require.Equal([]int64{0}, AndCardinalities(New(), []*Bitmap{fromPositions(1, 2)}))
+	require.Empty(AndCardinalities(fromPositions(1, 2), nil))
+}
+
+func TestCardinalityCached(t *testing.T) {
+	require := require.New(t)
+	r := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 20; i++ {
+		b := New()
+		require.Equal(int64(0), b.Cardinality())
+		require.True(b.hasCard)
+
+		for j := 0; j < 200; j++ {
+			pos := int64(r.Intn(20000))
+			switch r.Intn(7) {
+			case 0:
+				require.NoError(b.Set(b.n + pos%300))
+			case 1:
+				require.NoError(b.SetRange(b.n+pos%100, b.n+pos%1000))
+			case 2:
+				require.NoError(b.AddMany([]int64{b.n + pos%70, b.n + pos%70 + 3, b.n + pos%70 + 300}))
+			case 3:
+				require.NoError(b.Clear(pos))
+			case 4:
+				_, err := b.Toggle(pos)
+				require.NoError(err)
+			case 5:
+				require.NoError(b.SetAnywhere(pos))
+			case 6:
+				require.NoError(b.OrInPlace(fromPositions(randomPositions(r, 5000, 0.1)...)))
+			}
+			require.Equal(b.cardinality(), b.Cardinality())
+		}
+
+		require.NoError(b.Truncate(b.n / 2))
+		require.False(b.hasCard)
+		require.Equal(b.cardinality(), b.Cardinality())
+	}
+
+	a := fromPositions(1, 2, 3, 500)
+	require.Equal(int64(3), a.And(fromPositions(2, 3, 500)).Cardinality())
+	require.Equal(int64(5), a.Xor(fromPositions(3, 4, 5)).Cardinality())
+	require.Equal(int64(1000), zeroes(1000).Not().Cardinality())
+
+	a.Freeze()
+	a.hasCard = false
+	require.Equal(int64(4), a.Cardinality())
+	require.False(a.hasCard)
+}
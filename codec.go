@@ -0,0 +1,88 @@
+package ewah
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// Codec is the encoding used for a bitmap in a framed serialization.
+type Codec byte
+
+const (
+	// CodecAuto picks the codec that takes less space for each bitmap. It
+	// is only valid when writing.
+	CodecAuto Codec = iota
+	// CodecEWAH uses the EWAH serialization written by Write.
+	CodecEWAH
+	// CodecEliasFano uses Elias-Fano encoding of the set positions, which
+	// takes much less space than EWAH for very sparse bitmaps.
+	CodecEliasFano
+)
+
+// frameMagic identifies a framed serialization.
+var frameMagic = []byte("EWHF")
+
+const frameVersion = 1
+
+// WriteFramed writes the bitmap with a small header identifying the codec
+// used to encode it, so ReadFramed can decode it regardless of the codec. All
+// numbers are written in big endian order.
+func (b *Bitmap) WriteFramed(w io.Writer, codec Codec) (int64, error) {
+	if codec == CodecAuto {
+		codec = CodecEWAH
+		if b.eliasFanoSize() < b.ewahSize() {
+			codec = CodecEliasFano
+		}
+	}
+
+	header := append(append([]byte{}, frameMagic...), frameVersion, byte(codec))
+	if _, err := w.Write(header); err != nil {
+		return 0, err
+	}
+
+	var n int64
+	var err error
+	switch codec {
+	case CodecEWAH:
+		n, err = b.Write(w, binary.BigEndian)
+	case CodecEliasFano:
+		n, err = b.writeEliasFano(w)
+	default:
+		return 0, fmt.Errorf("bitmap: unknown codec %d", codec)
+	}
+
+	return int64(len(header)) + n, err
+}
+
+// ReadFramed reads a bitmap written with WriteFramed.
+func ReadFramed(r io.Reader) (*Bitmap, error) {
+	header := make([]byte, len(frameMagic)+2)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, fmt.Errorf("bitmap: can't read frame header: %s", err)
+	}
+
+	if !bytes.Equal(header[:len(frameMagic)], frameMagic) {
+		return nil, fmt.Errorf("bitmap: invalid frame magic %q", header[:len(frameMagic)])
+	}
+
+	if v := header[len(frameMagic)]; v != frameVersion {
+		return nil, fmt.Errorf("bitmap: unsupported frame version %d", v)
+	}
+
+	switch codec := Codec(header[len(frameMagic)+1]); codec {
+	case CodecEWAH:
+		return FromReader(r, binary.BigEndian)
+	case CodecEliasFano:
+		return readEliasFano(r)
+	default:
+		return nil, fmt.Errorf("bitmap: unknown codec %d", codec)
+	}
+}
+
+// ewahSize returns the number of bytes taken by the bitmap when written with
+// Write.
+func (b *Bitmap) ewahSize() int64 {
+	return 4*3 + int64(len(b.basePrefix())+len(b.w))*8
+}
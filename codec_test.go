@@ -0,0 +1,109 @@
+package ewah
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFramedRoundTrip(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+
+	for _, codec := range []Codec{CodecAuto, CodecEWAH, CodecEliasFano} {
+		require := require.New(t)
+		for i := 0; i < 30; i++ {
+			var ps []int64
+			if i%2 == 0 {
+				ps = randomPositions(r, int64(r.Intn(20000)), r.Float64())
+			} else {
+				for p := int64(r.Intn(100)); p < 1000000; p += int64(r.Intn(100000)) + 1 {
+					ps = append(ps, p)
+				}
+			}
+
+			b := fromPositions(ps...)
+			b.extend(b.n + int64(r.Intn(100)))
+
+			buf := bytes.NewBuffer(nil)
+			n, err := b.WriteFramed(buf, codec)
+			require.NoError(err)
+			require.Equal(int64(buf.Len()), n)
+
+			result, err := ReadFramed(buf)
+			require.NoError(err)
+			require.Equal(ps, positions(result))
+			require.Equal(b.n, result.n)
+			require.Equal(wordsFor(b.n), countWords(result))
+		}
+	}
+}
+
+func TestFramedAuto(t *testing.T) {
+	require := require.New(t)
+
+	sparse := fromPositions(5, 100000, 2000000, 30000000)
+	buf := bytes.NewBuffer(nil)
+	_, err := sparse.WriteFramed(buf, CodecAuto)
+	require.NoError(err)
+	require.Equal(byte(CodecEliasFano), buf.Bytes()[5])
+	require.True(sparse.eliasFanoSize() < sparse.ewahSize())
+
+	dense := newBitmap()
+	buf.Reset()
+	_, err = dense.WriteFramed(buf, CodecAuto)
+	require.NoError(err)
+	require.Equal(byte(CodecEWAH), buf.Bytes()[5])
+
+	buf.Reset()
+	_, err = New().WriteFramed(buf, CodecEliasFano)
+	require.NoError(err)
+	result, err := ReadFramed(buf)
+	require.NoError(err)
+	require.Equal(New(), result)
+}
+
+func TestReadFramedErrors(t *testing.T) {
+	testCases := []struct {
+		data []byte
+		err  string
+	}{
+		{[]byte("EW"), "bitmap: can't read frame header: unexpected EOF"},
+		{[]byte("EWAH\x01\x01"), `bitmap: invalid frame magic "EWAH"`},
+		{[]byte("EWHF\x02\x01"), "bitmap: unsupported frame version 2"},
+		{[]byte("EWHF\x01\x09"), "bitmap: unknown codec 9"},
+		{
+			[]byte("EWHF\x01\x02\x00\x00\x00\x00\x00\x00\x00\x01\x00\x00\x00\x00\x00\x00\x00\x02\x00"),
+			"bitmap: invalid elias-fano header with 1 bits, 2 positions and 0 low bits",
+		},
+		{
+			[]byte("EWHF\x01\x02\x00\x00\x00\x00\x00\x00\x00\x02\x00\x00\x00\x00\x00\x00\x00\x01\x01" +
+				"\x00\x00\x00\x00\x00\x00\x00\x00\x40\x00\x00\x00\x00\x00\x00\x00"),
+			"bitmap: invalid elias-fano position 2",
+		},
+	}
+
+	for _, tt := range testCases {
+		_, err := ReadFramed(bytes.NewReader(tt.data))
+		require.EqualError(t, err, tt.err)
+	}
+}
+
+func TestReadFramedCorruptEliasFanoHeader(t *testing.T) {
+	require := require.New(t)
+
+	// 2^62 bits with 2^61 positions claim about 2^56 words
+	header := []byte("EWHF\x01\x02\x40\x00\x00\x00\x00\x00\x00\x00\x20\x00\x00\x00\x00\x00\x00\x00\x01")
+	data := append(header, make([]byte, 16)...)
+
+	_, err := ReadFramed(bytes.NewReader(data))
+	require.Error(err)
+	require.True(errors.Is(err, io.ErrUnexpectedEOF))
+
+	// readers that don't know their length fail once they run out of data
+	_, err = ReadFramed(io.MultiReader(bytes.NewReader(data)))
+	require.EqualError(err, "bitmap: can't read 3th word: EOF")
+}
@@ -0,0 +1,49 @@
+package ewah
+
+// Compact returns a new bitmap without the positions set in deleted, where
+// every remaining position is shifted down by the number of deleted positions
+// before it. The result has as many bits as the bitmap minus the deleted
+// positions within it.
+func (b *Bitmap) Compact(deleted *Bitmap) *Bitmap {
+	del := deleted.bits()
+	d, ok := del.next()
+	var removed int64
+	skip := func(pos int64) {
+		for ok && d < pos {
+			removed++
+			d, ok = del.next()
+		}
+	}
+
+	result, _ := b.ApplyMapping(func(pos int64) int64 {
+		skip(pos)
+		if ok && d == pos {
+			return -1
+		}
+		return pos - removed
+	})
+
+	skip(b.n)
+	result.extend(b.n - removed)
+	return result
+}
+
+// CompactMapping returns the table of new positions that Compact assigns to
+// the first n positions, with -1 for the deleted ones. It can be used with
+// ApplyMappingSlice to renumber other bitmaps the same way.
+func CompactMapping(deleted *Bitmap, n int64) []int64 {
+	mapping := make([]int64, n)
+	del := deleted.bits()
+	d, ok := del.next()
+	var removed int64
+	for pos := range mapping {
+		if ok && d == int64(pos) {
+			mapping[pos] = -1
+			removed++
+			d, ok = del.next()
+		} else {
+			mapping[pos] = int64(pos) - removed
+		}
+	}
+	return mapping
+}
@@ -0,0 +1,43 @@
+package ewah
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompact(t *testing.T) {
+	require := require.New(t)
+
+	b := fromPositions(0, 2, 3, 5, 70, 130)
+	deleted := fromPositions(1, 2, 64, 65, 500)
+
+	result := b.Compact(deleted)
+	require.Equal([]int64{0, 1, 3, 66, 126}, positions(result))
+	require.Equal(int64(131-4), result.n)
+	require.Equal(wordsFor(result.n), countWords(result))
+}
+
+func TestCompactRandom(t *testing.T) {
+	require := require.New(t)
+	r := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 20; i++ {
+		b := fromPositions(randomPositions(r, 3000, 0.5)...)
+		deleted := fromPositions(randomPositions(r, 3000, 0.3)...)
+
+		mapping := CompactMapping(deleted, b.n)
+		expected, err := b.ApplyMappingSlice(mapping)
+		require.NoError(err)
+
+		require.Equal(positions(expected), positions(b.Compact(deleted)))
+	}
+}
+
+func TestCompactMapping(t *testing.T) {
+	require.Equal(t,
+		[]int64{0, -1, -1, 1, 2, -1},
+		CompactMapping(fromPositions(1, 2, 5, 100), 6),
+	)
+}
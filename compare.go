@@ -0,0 +1,21 @@
+package ewah
+
+// Equal reports whether the bitmap and other have the same bits set. Only
+// the set bits are compared, so bitmaps encoded with different words, or
+// with a different number of trailing zeroes, can be equal. Both are walked
+// in lockstep, stopping at the first difference.
+func (b *Bitmap) Equal(other *Bitmap) bool {
+	return !mergeAny(b, other, opXor)
+}
+
+// IsSubsetOf reports whether all the bits set in the bitmap are set in other
+// too. Both are walked in lockstep, stopping at the first bit that is not.
+func (b *Bitmap) IsSubsetOf(other *Bitmap) bool {
+	return !mergeAny(b, other, opAndNot)
+}
+
+// IsSupersetOf reports whether all the bits set in other are set in the
+// bitmap too.
+func (b *Bitmap) IsSupersetOf(other *Bitmap) bool {
+	return other.IsSubsetOf(b)
+}
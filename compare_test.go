@@ -0,0 +1,69 @@
+package ewah
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEqual(t *testing.T) {
+	require := require.New(t)
+	r := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 30; i++ {
+		ps := randomPositions(r, int64(r.Intn(10000)), r.Float64()*r.Float64())
+		a := fromPositions(ps...)
+
+		b := a.Clone()
+		b.extend(b.n + int64(r.Intn(100000)))
+		require.True(a.Equal(b))
+		require.True(b.Equal(a))
+
+		// the same bits set one by one and merged from two halves
+		half := len(ps) / 2
+		c := or(fromPositions(ps[:half]...), fromPositions(ps[half:]...))
+		require.True(a.Equal(c))
+
+		if len(ps) > 0 {
+			d := fromPositions(ps[1:]...)
+			require.False(a.Equal(d))
+			require.False(d.Equal(a))
+		}
+	}
+
+	base := NewWithBase(6400)
+	require.NoError(base.Set(6500))
+	require.True(base.Equal(fromPositions(6500)))
+	require.True(New().Equal(zeroes(1000)))
+	require.False(New().Equal(zeroes(1000).Not()))
+}
+
+func TestSubset(t *testing.T) {
+	require := require.New(t)
+	r := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 30; i++ {
+		ps := randomPositions(r, int64(r.Intn(10000)), r.Float64())
+		a := fromPositions(ps...)
+
+		var sub []int64
+		for _, p := range ps {
+			if r.Intn(4) > 0 {
+				sub = append(sub, p)
+			}
+		}
+		b := fromPositions(sub...)
+
+		require.True(b.IsSubsetOf(a))
+		require.True(a.IsSupersetOf(b))
+		require.True(a.IsSubsetOf(a))
+		require.Equal(len(sub) == len(ps), a.IsSubsetOf(b))
+		require.Equal(len(sub) == len(ps), b.IsSupersetOf(a))
+	}
+
+	require.True(New().IsSubsetOf(fromPositions(1)))
+	require.False(fromPositions(1).IsSubsetOf(New()))
+	require.True(fromPositions(1, 5000).IsSubsetOf(zeroes(6000).Not()))
+	require.False(fromPositions(1, 7000).IsSubsetOf(zeroes(6000).Not()))
+}
@@ -0,0 +1,94 @@
+package ewah
+
+import "math"
+
+// Complement is a read-only view of the complement of a bitmap within a
+// universe of bits. It answers queries and takes part in the logical
+// operations, such as AndTo, flipping the words of the bitmap on the fly, so
+// the complement is never materialized. The bitmap must not be modified
+// while the view is in use.
+type Complement struct {
+	b *Bitmap
+	n int64
+}
+
+// Complement returns a view of the complement of the bitmap within a
+// universe of the given number of bits. A universe smaller than the bitmap
+// is raised to its number of bits.
+func (b *Bitmap) Complement(universe int64) *Complement {
+	return &Complement{b: b, n: maxInt64(universe, b.n)}
+}
+
+// Bits returns the number of bits in the universe of the complement.
+func (c *Complement) Bits() uint32 {
+	return uint32(c.n)
+}
+
+// Get returns the bit at the given position, being true 1 and false 0.
+func (c *Complement) Get(pos int64) bool {
+	return pos >= 0 && pos < c.n && !c.b.Get(pos)
+}
+
+// Iterator returns an iterator over the positions of the set bits of the
+// complement.
+func (c *Complement) Iterator() *Iterator {
+	return c.IteratorRange(0, math.MaxInt64)
+}
+
+// IteratorRange returns an iterator over the positions of the set bits of
+// the complement in the interval [from, to).
+func (c *Complement) IteratorRange(from, to int64) *Iterator {
+	bits := &bitIterator{it: c.runsFrom(0)}
+	if from > 0 {
+		bits.seek(from)
+	}
+	return &Iterator{bits: bits, to: minInt64(to, c.n)}
+}
+
+func (c *Complement) bitLen() int64 { return c.n }
+
+// start is always 0, as the positions before the base of the bitmap are set
+// in its complement.
+func (c *Complement) start() int64 { return 0 }
+
+func (c *Complement) runsFrom(pos int64) runIterator {
+	b := c.b
+	covered, total := wordsFor(b.n), wordsFor(c.n)
+	it := runIterator{
+		w:    b.w,
+		bit:  true,
+		run:  (b.base - pos) / 64,
+		flip: allones,
+		left: covered - b.base/64,
+	}
+
+	// the words after the bitmap are all ones up to the end of the
+	// universe, where the bits left in the last word must be unset
+	suffix := New()
+	if rem := c.n % 64; rem == 0 {
+		suffix.appendClean(true, total-covered)
+	} else if mask := allones << (64 - uint64(rem)); total > covered {
+		suffix.appendClean(true, total-covered-1)
+		suffix.appendLiteral(mask)
+	} else {
+		it.left--
+		suffix.appendLiteral(^b.lastWord() & mask)
+	}
+
+	it.suffix = suffix.w
+	it.advance()
+	return it
+}
+
+// lastWord returns the last word of the bitmap, or zero if it has none.
+func (b *Bitmap) lastWord() uint64 {
+	if b.lastrlw < 0 || b.lastrlw >= len(b.w) {
+		return 0
+	}
+
+	r := rlw(b.w[b.lastrlw])
+	if r.l() > 0 {
+		return b.w[len(b.w)-1]
+	}
+	return fill(r.b() && r.k() > 0)
+}
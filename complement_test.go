@@ -0,0 +1,75 @@
+package ewah
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestComplement(t *testing.T) {
+	require := require.New(t)
+
+	b := fromPositions(1, 2, 64, 65, 130, 200)
+	c := b.Complement(260)
+
+	require.Equal(uint32(260), c.Bits())
+	require.False(c.Get(1))
+	require.True(c.Get(3))
+	require.True(c.Get(259))
+	require.False(c.Get(260))
+	require.False(c.Get(-1))
+
+	var expected []int64
+	for i := int64(0); i < 260; i++ {
+		if !b.Get(i) {
+			expected = append(expected, i)
+		}
+	}
+	require.Equal(expected, iterate(c.Iterator()))
+	require.Equal(expected[60:100], iterate(c.IteratorRange(expected[60], expected[100])))
+
+	require.Equal(uint32(201), b.Complement(10).Bits())
+}
+
+func TestComplementOperand(t *testing.T) {
+	require := require.New(t)
+	r := rand.New(rand.NewSource(1))
+
+	ops := []func(dst *Bitmap, a, b Operand) *Bitmap{AndTo, OrTo, XorTo, AndNotTo}
+	for i := 0; i < 50; i++ {
+		b := New()
+		if i%2 == 0 {
+			b = NewWithBase(int64(r.Intn(3000)))
+		}
+		for _, p := range randomPositions(r, 3000, r.Float64()) {
+			if p >= b.Base() {
+				require.NoError(b.Set(p))
+			}
+		}
+
+		universe := b.n + int64(r.Intn(200))
+		if i%5 == 0 {
+			universe = wordsFor(b.n) * 64
+		}
+
+		c := b.Complement(universe)
+		complement := not(b, universe)
+		requireSameBits(t, complement, OrTo(New(), c, New()))
+		require.Equal(positions(complement), iterate(c.Iterator()))
+
+		other := fromPositions(randomPositions(r, 3500, r.Float64())...)
+		for _, op := range ops {
+			requireSameBits(t, op(New(), complement, other), op(New(), c, other))
+			requireSameBits(t, op(New(), other, complement), op(New(), other, c))
+		}
+	}
+}
+
+// requireSameBits checks that both bitmaps have the same size and bits set,
+// no matter how their words are encoded.
+func requireSameBits(t *testing.T, expected, actual *Bitmap) {
+	t.Helper()
+	require.Equal(t, expected.n, actual.n)
+	require.Equal(t, positions(expected), positions(actual))
+}
@@ -0,0 +1,99 @@
+package ewah
+
+import "math/bits"
+
+// maxConciseFill is the maximum number of groups, minus one, in a Concise
+// fill word.
+const maxConciseFill = 1<<25 - 1
+
+// ToConcise converts the bitmap to the Concise encoding, which is like WAH
+// with a few differences: literals have the most significant bit set and
+// the 31 bits of the group in the rest, starting from bit 0, and fills have
+// it unset, the value of the fill in bit 30, the number of groups it covers
+// minus one in bits 0 to 24 and, in bits 25 to 29, the position plus one of
+// a bit of the first group that has the opposite value, or 0 if there is
+// none. Such fills absorb literals that have a single bit different from
+// the fill that follows them.
+func (b *Bitmap) ToConcise() []uint32 {
+	var out []uint32
+	b.groups(&groupBuilder{
+		literal: func(group uint32) {
+			out = append(out, 1<<31|reverse31(group))
+		},
+		fill: func(bit bool, groups int64) {
+			// a previous literal with a single bit different from the fill
+			// becomes its first group
+			if last := len(out) - 1; last >= 0 && out[last]>>31 == 1 {
+				payload := out[last] & groupMask
+				if bit {
+					payload ^= groupMask
+				}
+
+				if bits.OnesCount32(payload) == 1 {
+					add := minInt64(groups, maxConciseFill)
+					pos := uint32(bits.TrailingZeros32(payload)) + 1
+					out[last] = conciseFill(bit, pos, uint32(add))
+					groups -= add
+				}
+			}
+
+			for groups > 0 {
+				if last := len(out) - 1; last >= 0 && out[last]>>30 == conciseFill(bit, 0, 0)>>30 && out[last]&maxConciseFill < maxConciseFill {
+					add := minInt64(groups, int64(maxConciseFill-out[last]&maxConciseFill))
+					out[last] += uint32(add)
+					groups -= add
+					continue
+				}
+
+				add := minInt64(groups, maxConciseFill+1)
+				out = append(out, conciseFill(bit, 0, uint32(add-1)))
+				groups -= add
+			}
+		},
+	})
+	return out
+}
+
+// FromConcise creates a bitmap of n bits from its Concise encoding, as
+// described in ToConcise.
+func FromConcise(words []uint32, n int64) (*Bitmap, error) {
+	d := newGroupDecoder(n)
+	for _, w := range words {
+		if w>>31 == 1 {
+			d.addGroup(reverse31(w))
+		} else {
+			bit := w>>30&1 == 1
+			first := uint32(0)
+			if bit {
+				first = groupMask
+			}
+
+			if pos := w >> 25 & 0x1f; pos > 0 {
+				first ^= 1 << (groupBits - pos)
+			}
+
+			d.addGroup(first)
+			if d.err == nil {
+				d.addRun(bit, int64(w&maxConciseFill)*groupBits)
+			}
+		}
+
+		if d.err != nil {
+			return nil, d.err
+		}
+	}
+	return d.bitmap(), nil
+}
+
+func conciseFill(bit bool, pos, groups uint32) uint32 {
+	w := pos<<25 | groups
+	if bit {
+		w |= 1 << 30
+	}
+	return w
+}
+
+// reverse31 reverses the order of the lower 31 bits of x.
+func reverse31(x uint32) uint32 {
+	return bits.Reverse32(x&groupMask) >> 1
+}
@@ -0,0 +1,59 @@
+package ewah
+
+// Cursor reads the bits of a bitmap at ascending positions, resuming every
+// read from the marker where the previous one stopped instead of scanning
+// the words from the start, so reading n positions in order takes time
+// linear in n plus the number of words. Reading a position lower than the
+// previous one moves the cursor back to the start, or to the closest indexed
+// marker if the bitmap is frozen. Unlike Get, a cursor keeps its state to
+// itself, so any number of goroutines can read a frozen bitmap at once, each
+// with its own cursor. The bitmap must not be modified while the cursor is
+// in use.
+type Cursor struct {
+	b *Bitmap
+	// word is the index of the current marker.
+	word int
+	// offset is the position, relative to the base, of the first bit
+	// covered by the current marker.
+	offset int64
+}
+
+// Cursor returns a cursor positioned at the first marker of the bitmap.
+func (b *Bitmap) Cursor() *Cursor {
+	return &Cursor{b: b}
+}
+
+// Get returns whether the bit at the given position is set.
+func (c *Cursor) Get(pos int64) bool {
+	b := c.b
+	if pos >= b.n || pos < b.base {
+		return false
+	}
+
+	pos -= b.base
+	if pos < c.offset {
+		c.word, c.offset = b.seekIndex(pos)
+	}
+
+	for c.word < len(b.w) {
+		r := rlw(b.w[c.word])
+		run, lits := int64(r.k())*64, int64(r.l())
+		if pos < c.offset+run {
+			return r.b()
+		}
+
+		if rel := pos - c.offset - run; rel < lits*64 {
+			idx := c.word + 1 + int(rel/64)
+			// a corrupt marker may have more literals than words
+			if idx >= len(b.w) {
+				return false
+			}
+			return b.w[idx]&(bmask>>uint64(rel%64)) != 0
+		}
+
+		c.offset += run + lits*64
+		c.word += int(lits) + 1
+	}
+
+	return false
+}
@@ -0,0 +1,44 @@
+package ewah
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCursor(t *testing.T) {
+	require := require.New(t)
+	r := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 20; i++ {
+		base := int64(r.Intn(3)) * 640
+		ps := randomPositions(r, int64(r.Intn(100000)), r.Float64()*r.Float64())
+		b := NewWithBase(base)
+		for _, p := range ps {
+			require.NoError(b.Set(p + base))
+		}
+
+		expected := b.Clone()
+		if r.Intn(2) == 0 {
+			b.Freeze()
+		}
+
+		c := b.Cursor()
+		var pos int64
+		for pos < b.n+100 {
+			require.Equal(expected.Get(pos), c.Get(pos), "position %d", pos)
+			pos += int64(r.Intn(200))
+			if r.Intn(20) == 0 {
+				pos -= int64(r.Intn(1000))
+			}
+		}
+	}
+
+	c := zeroes(1000).Not().Cursor()
+	require.True(c.Get(999))
+	require.False(c.Get(1000))
+	require.False(c.Get(-1))
+	require.True(c.Get(0))
+	require.False(New().Cursor().Get(0))
+}
@@ -0,0 +1,13 @@
+//go:build ewah_debug
+// +build ewah_debug
+
+package ewah
+
+// assertInvariants panics if the encoding of the bitmap is not consistent.
+// It is only enabled with the ewah_debug build tag, so corruption is caught
+// right where it happens instead of in some later read.
+func (b *Bitmap) assertInvariants() {
+	if err := b.checkInvariants(); err != nil {
+		panic(err)
+	}
+}
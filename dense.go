@@ -0,0 +1,52 @@
+package ewah
+
+import "math/bits"
+
+// FromDense creates a bitmap with the first nbits bits of an uncompressed
+// bitset, where the bit at position i is the bit i%64 of words[i/64],
+// counting from the least significant one, as most bitset libraries do.
+// Words that are all zeroes or all ones are added as runs. Words missing to
+// hold nbits bits are taken as zeroes, and the bits beyond nbits are ignored.
+func FromDense(words []uint64, nbits int64) *Bitmap {
+	s := newStreamBuilder()
+	for i := int64(0); i*64 < nbits; i++ {
+		var word uint64
+		if i < int64(len(words)) {
+			word = bits.Reverse64(words[i])
+		}
+
+		count := minInt64(64, nbits-i*64)
+		s.addBits(word&^(allones>>uint(count)), int(count))
+	}
+	return s.bitmap()
+}
+
+// ToDense returns the bitmap as an uncompressed bitset, in the layout read
+// by FromDense, with as many words as needed to hold its bits.
+func (b *Bitmap) ToDense() []uint64 {
+	return b.AppendDense(make([]uint64, 0, wordsFor(b.n)))
+}
+
+// AppendDense appends to dst the bitmap as an uncompressed bitset, like
+// ToDense, and returns the extended slice, so the memory of a previous
+// result can be reused.
+func (b *Bitmap) AppendDense(dst []uint64) []uint64 {
+	it := b.runs()
+	for words := wordsFor(b.n); words > 0 && !it.done(); {
+		if it.run > 0 {
+			k := minInt64(it.run, words)
+			word := fill(it.bit)
+			for i := int64(0); i < k; i++ {
+				dst = append(dst, word)
+			}
+			words -= k
+			it.discard(k)
+			continue
+		}
+
+		dst = append(dst, bits.Reverse64(it.literal(0)))
+		words--
+		it.discard(1)
+	}
+	return dst
+}
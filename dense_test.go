@@ -0,0 +1,71 @@
+package ewah
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFromDense(t *testing.T) {
+	require := require.New(t)
+	r := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 20; i++ {
+		n := int64(r.Intn(20000))
+		ps := randomPositions(r, n, r.Float64())
+		words := make([]uint64, wordsFor(n))
+		for _, p := range ps {
+			words[p/64] |= 1 << uint(p%64)
+		}
+
+		expected := fromPositions(ps...)
+		expected.extend(n)
+
+		b := FromDense(words, n)
+		require.Equal(ps, positions(b))
+		require.Equal(expected.w, b.w)
+		require.Equal(n, b.n)
+	}
+
+	b := FromDense([]uint64{allones, allones, 0, 0, 0, 1 << 3}, 200)
+	require.Equal(int64(128), b.Cardinality())
+	require.Equal(int64(200), b.n)
+	require.Len(b.w, 2)
+
+	b = FromDense([]uint64{allones}, 10)
+	require.Equal([]int64{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}, positions(b))
+	require.Equal(New(), FromDense(nil, 0))
+}
+
+func TestToDense(t *testing.T) {
+	require := require.New(t)
+	r := rand.New(rand.NewSource(1))
+
+	var buf []uint64
+	for i := 0; i < 20; i++ {
+		n := int64(r.Intn(20000))
+		ps := randomPositions(r, n, r.Float64())
+		b := fromPositions(ps...)
+		b.extend(n)
+
+		words := b.ToDense()
+		require.Len(words, int(wordsFor(n)))
+		for _, p := range ps {
+			require.NotZero(words[p/64] & (1 << uint(p%64)))
+		}
+		require.Equal(b.w, FromDense(words, n).w)
+
+		buf = b.AppendDense(buf[:0])
+		require.Equal(words, buf)
+	}
+
+	require.Equal([]uint64{allones, 1<<8 - 1}, zeroes(72).Not().ToDense())
+	require.Equal([]uint64{5}, fromPositions(0, 2).ToDense())
+	require.Empty(New().ToDense())
+
+	b := NewWithBase(6400)
+	require.NoError(b.Set(6400))
+	require.Equal(uint64(1), b.ToDense()[100])
+	require.Equal([]uint64{1, 2}, fromPositions(1).AppendDense([]uint64{1}))
+}
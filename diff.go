@@ -0,0 +1,131 @@
+package ewah
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// WriteDiff reads the bitmap of a replica from r, as written by Write, and
+// writes to w the positions that differ between it and b, that is, the ones
+// that have to be added to or removed from the replica. The replica is
+// streamed, one marker at a time, and xored run by run with the words of b,
+// so it is never held in memory. The difference is written as a bitmap in
+// the format of Write, so it takes little space when both bitmaps are alike,
+// no matter how large they are. The replica can be brought up to date using
+// ApplyDiff.
+func (b *Bitmap) WriteDiff(w io.Writer, r io.Reader, order binary.ByteOrder) (int64, error) {
+	bits, err := readUint32(r, order)
+	if err != nil {
+		return 0, fmt.Errorf("bitmap: can't read uncompressed bit number: %s", err)
+	}
+
+	words, err := readUint32(r, order)
+	if err != nil {
+		return 0, fmt.Errorf("bitmap: can't read compressed word number: %s", err)
+	}
+
+	out := New()
+	local := b.runs()
+	var pos int64
+	for i := uint32(0); i < words; {
+		word, err := readUint64(r, order)
+		if err != nil {
+			return 0, fmt.Errorf("bitmap: can't read %dth word: %s", i+1, err)
+		}
+		i++
+
+		marker := rlw(word)
+		xorRun(out, &local, marker.b(), int64(marker.k()))
+		pos += int64(marker.k())
+
+		for j := uint32(0); j < marker.l() && i < words; j++ {
+			word, err := readUint64(r, order)
+			if err != nil {
+				return 0, fmt.Errorf("bitmap: can't read %dth word: %s", i+1, err)
+			}
+			i++
+
+			out.appendLiteral(word ^ nextWord(&local))
+			pos++
+		}
+	}
+
+	if _, err := readUint32(r, order); err != nil {
+		return 0, fmt.Errorf("bitmap: can't read position of current RLW: %s", err)
+	}
+
+	// the words of b beyond the ones of the replica are xored with zeroes
+	for !local.done() {
+		k := local.run + int64(local.lits)
+		xorRun(out, &local, false, k)
+		pos += k
+	}
+
+	n := maxInt64(b.n, int64(bits))
+	if pos > wordsFor(n) {
+		return 0, &CorruptError{-1, fmt.Sprintf("replica has %d words, but %d bits need %d", pos, n, wordsFor(n))}
+	}
+
+	out.appendClean(false, wordsFor(n)-pos)
+	out.n = n
+	out.assertInvariants()
+	return out.Write(w, order)
+}
+
+// xorRun appends to out the next n words of it xored with a run of n clean
+// words of the given bit. Words beyond the end of it are seen as zeroes.
+func xorRun(out *Bitmap, it *runIterator, bit bool, n int64) {
+	for n > 0 {
+		if it.done() {
+			out.appendClean(bit, n)
+			return
+		}
+
+		if it.run > 0 {
+			k := minInt64(n, it.run)
+			out.appendClean(it.bit != bit, k)
+			it.discard(k)
+			n -= k
+			continue
+		}
+
+		k := int(minInt64(n, int64(it.lits)))
+		for j := 0; j < k; j++ {
+			out.appendLiteral(it.literal(j) ^ fill(bit))
+		}
+		it.discard(int64(k))
+		n -= int64(k)
+	}
+}
+
+// nextWord returns the next word of it, or zero if there are none left.
+func nextWord(it *runIterator) uint64 {
+	if it.done() {
+		return 0
+	}
+
+	word := fill(it.bit)
+	if it.run == 0 {
+		word = it.literal(0)
+	}
+	it.discard(1)
+	return word
+}
+
+// ApplyDiff reads a difference written by WriteDiff from r and toggles the
+// positions in it, which makes the bitmap equal to the one the difference
+// was computed from. The bitmap keeps at least as many bits as it had.
+func (b *Bitmap) ApplyDiff(r io.Reader, order binary.ByteOrder) error {
+	if b.frozen {
+		return ErrFrozen
+	}
+
+	diff, err := FromReader(r, order)
+	if err != nil {
+		return err
+	}
+
+	b.replace(xor(b, diff))
+	return nil
+}
@@ -0,0 +1,84 @@
+package ewah
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiff(t *testing.T) {
+	require := require.New(t)
+	r := rand.New(rand.NewSource(1))
+
+	ps := randomPositions(r, 100000, 0.5)
+	replica := fromPositions(ps...)
+
+	// the local bitmap drops a few positions and adds a few more at the end
+	local := New()
+	for i, p := range ps {
+		if i%1000 != 0 {
+			require.NoError(local.Set(p))
+		}
+	}
+	for p := int64(100000); p < 100500; p += 7 {
+		require.NoError(local.Set(p))
+	}
+
+	var remote, diff bytes.Buffer
+	_, err := replica.Write(&remote, binary.BigEndian)
+	require.NoError(err)
+	size := remote.Len()
+
+	_, err = local.WriteDiff(&diff, &remote, binary.BigEndian)
+	require.NoError(err)
+	require.True(diff.Len() < size/4, "diff takes %d of %d bytes", diff.Len(), size)
+
+	require.NoError(replica.ApplyDiff(&diff, binary.BigEndian))
+	require.Equal(positions(local), positions(replica))
+	require.NoError(replica.Set(200000))
+
+	replica.Freeze()
+	require.Equal(ErrFrozen, replica.ApplyDiff(&diff, binary.BigEndian))
+
+	_, err = local.WriteDiff(&diff, bytes.NewReader([]byte{1, 2}), binary.BigEndian)
+	require.Error(err)
+
+	// the difference is the same as xoring both bitmaps
+	for i := 0; i < 20; i++ {
+		a := fromPositions(randomPositions(r, int64(r.Intn(20000)), r.Float64())...)
+		b := NewWithBase(int64(r.Intn(3)) * 640)
+		for _, p := range randomPositions(r, int64(r.Intn(20000)), r.Float64()) {
+			if p >= b.Base() {
+				require.NoError(b.Set(p))
+			}
+		}
+
+		remote.Reset()
+		_, err := a.Write(&remote, binary.LittleEndian)
+		require.NoError(err)
+
+		diff.Reset()
+		n, err := b.WriteDiff(&diff, &remote, binary.LittleEndian)
+		require.NoError(err)
+		require.Equal(int64(diff.Len()), n)
+
+		result, err := FromBytes(diff.Bytes(), binary.LittleEndian)
+		require.NoError(err)
+		require.NoError(result.Validate())
+		expected := xor(b, a)
+		require.Equal(expected.n, result.n)
+		require.Equal(positions(expected), positions(result))
+	}
+
+	// truncated replicas
+	remote.Reset()
+	_, err = replica.Write(&remote, binary.BigEndian)
+	require.NoError(err)
+	for _, size := range []int{4, 8, 20, remote.Len() - 1} {
+		_, err := local.WriteDiff(&diff, bytes.NewReader(remote.Bytes()[:size]), binary.BigEndian)
+		require.Error(err, "size %d", size)
+	}
+}
@@ -0,0 +1,168 @@
+package ewah
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/bits"
+)
+
+// eliasFanoLowBits returns the number of low bits stored for each of m
+// positions in a universe of n bits.
+func eliasFanoLowBits(n, m int64) uint {
+	if m == 0 || n <= m {
+		return 0
+	}
+	return uint(63 - bits.LeadingZeros64(uint64(n/m)))
+}
+
+// eliasFanoWords returns the number of words taken by the low and high
+// parts of m positions in a universe of n bits using l low bits.
+func eliasFanoWords(n, m int64, l uint) (low, high int64) {
+	if m == 0 {
+		return 0, 0
+	}
+	return wordsFor(m * int64(l)), wordsFor(m + n>>l + 1)
+}
+
+// eliasFanoSize returns the number of bytes taken by the bitmap when written
+// with writeEliasFano.
+func (b *Bitmap) eliasFanoSize() int64 {
+	m := b.cardinality()
+	low, high := eliasFanoWords(b.n, m, eliasFanoLowBits(b.n, m))
+	return 8*2 + 1 + (low+high)*8
+}
+
+// writeEliasFano writes the set positions of the bitmap using Elias-Fano
+// encoding: the number of bits, the number of positions, the number of low
+// bits l, the words with the l low bits of every position and the words with
+// the rest of the bits of every position in unary.
+func (b *Bitmap) writeEliasFano(w io.Writer) (int64, error) {
+	m := b.cardinality()
+	l := eliasFanoLowBits(b.n, m)
+	nlow, nhigh := eliasFanoWords(b.n, m, l)
+	low := make([]uint64, nlow)
+	high := make([]uint64, nhigh)
+
+	var i int64
+	b.each(func(pos int64) bool {
+		if l > 0 {
+			writeBits(low, i*int64(l), uint64(pos)&(1<<l-1), l)
+		}
+
+		h := pos>>l + i
+		high[h/64] |= bmask >> uint64(h%64)
+		i++
+		return true
+	})
+
+	if err := writeUint64(w, binary.BigEndian, uint64(b.n)); err != nil {
+		return 0, err
+	}
+
+	if err := writeUint64(w, binary.BigEndian, uint64(m)); err != nil {
+		return 0, err
+	}
+
+	if _, err := w.Write([]byte{byte(l)}); err != nil {
+		return 0, err
+	}
+
+	for _, words := range [][]uint64{low, high} {
+		for _, word := range words {
+			if err := writeUint64(w, binary.BigEndian, word); err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	return 8*2 + 1 + (nlow+nhigh)*8, nil
+}
+
+func readEliasFano(r io.Reader) (*Bitmap, error) {
+	n, err := readUint64(r, binary.BigEndian)
+	if err != nil {
+		return nil, fmt.Errorf("bitmap: can't read uncompressed bit number: %s", err)
+	}
+
+	m, err := readUint64(r, binary.BigEndian)
+	if err != nil {
+		return nil, fmt.Errorf("bitmap: can't read number of positions: %s", err)
+	}
+
+	var lb [1]byte
+	if _, err := io.ReadFull(r, lb[:]); err != nil {
+		return nil, fmt.Errorf("bitmap: can't read number of low bits: %s", err)
+	}
+
+	l := uint(lb[0])
+	if int64(n) < 0 || m > n || l != eliasFanoLowBits(int64(n), int64(m)) {
+		return nil, fmt.Errorf("bitmap: invalid elias-fano header with %d bits, %d positions and %d low bits", n, m, l)
+	}
+
+	nlow, nhigh := eliasFanoWords(int64(n), int64(m), l)
+	total := nlow + nhigh
+	if total < 0 {
+		return nil, fmt.Errorf("bitmap: invalid elias-fano header with %d bits, %d positions and %d low bits", n, m, l)
+	}
+
+	// the header is not trusted to allocate memory up front, as in
+	// FromReaderWithOptions
+	if left, ok := bytesLeft(r); ok && total > left/8 {
+		return nil, fmt.Errorf("bitmap: can't read %d words from %d bytes: %w", total, left, io.ErrUnexpectedEOF)
+	}
+
+	words := make([]uint64, 0, minInt64(total, readChunk))
+	for i := int64(0); i < total; i++ {
+		word, err := readUint64(r, binary.BigEndian)
+		if err != nil {
+			return nil, fmt.Errorf("bitmap: can't read %dth word: %s", i+1, err)
+		}
+		words = append(words, word)
+	}
+	low, high := words[:nlow], words[nlow:]
+
+	b := New()
+	var i int64
+	for h, word := range high {
+		for ; word != 0 && i < int64(m); i++ {
+			idx := bits.LeadingZeros64(word)
+			word &^= bmask >> uint(idx)
+
+			pos := (int64(h)*64+int64(idx)-i)<<l | int64(readBits(low, i*int64(l), l))
+			if err := b.Set(pos); err != nil || pos >= int64(n) {
+				return nil, fmt.Errorf("bitmap: invalid elias-fano position %d", pos)
+			}
+		}
+	}
+
+	if i != int64(m) {
+		return nil, fmt.Errorf("bitmap: expecting %d elias-fano positions, found %d", m, i)
+	}
+
+	b.extend(int64(n))
+	return b, nil
+}
+
+// writeBits stores the l low bits of v at the given bit offset of words.
+func writeBits(words []uint64, offset int64, v uint64, l uint) {
+	i, shift := offset/64, uint(offset%64)
+	words[i] |= v << (64 - l) >> shift
+	if shift+l > 64 {
+		words[i+1] |= v << (128 - l - shift)
+	}
+}
+
+// readBits returns the l bits stored at the given bit offset of words.
+func readBits(words []uint64, offset int64, l uint) uint64 {
+	if l == 0 {
+		return 0
+	}
+
+	i, shift := offset/64, uint(offset%64)
+	v := words[i] << shift >> (64 - l)
+	if shift+l > 64 {
+		v |= words[i+1] >> (128 - l - shift)
+	}
+	return v
+}
@@ -0,0 +1,169 @@
+// Package eqindex implements equality-encoded bitmap indexes, which index a
+// column with one bitmap per distinct value holding the rows with it.
+package eqindex
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+
+	ewah "github.com/erizocosmico/go-ewah"
+)
+
+// Index is an equality-encoded bitmap index over a column of values.
+type Index struct {
+	bitmaps map[string]*ewah.Bitmap
+}
+
+// New creates an empty index.
+func New() *Index {
+	return &Index{bitmaps: make(map[string]*ewah.Bitmap)}
+}
+
+// Add adds the row with the given value. Like in Bitmap.Set, the rows of
+// every value need to be added in ascending order, and adding a row again
+// has no effect.
+func (i *Index) Add(row int64, value string) error {
+	b, ok := i.bitmaps[value]
+	if !ok {
+		b = ewah.New()
+	}
+
+	if err := b.Set(row); err != nil {
+		return err
+	}
+
+	i.bitmaps[value] = b
+	return nil
+}
+
+// Query returns the rows with the given value.
+func (i *Index) Query(value string) *ewah.Bitmap {
+	b, ok := i.bitmaps[value]
+	if !ok {
+		return ewah.New()
+	}
+	return b.Clone()
+}
+
+// QueryIn returns the rows with any of the given values.
+func (i *Index) QueryIn(values ...string) *ewah.Bitmap {
+	result := ewah.New()
+	for _, v := range values {
+		if b, ok := i.bitmaps[v]; ok {
+			result = ewah.OrTo(ewah.New(), result, b)
+		}
+	}
+	return result
+}
+
+// Values returns the distinct values in the index, sorted.
+func (i *Index) Values() []string {
+	values := make([]string, 0, len(i.bitmaps))
+	for v := range i.bitmaps {
+		values = append(values, v)
+	}
+	sort.Strings(values)
+	return values
+}
+
+// Distinct returns the number of distinct values in the index.
+func (i *Index) Distinct() int {
+	return len(i.bitmaps)
+}
+
+// Count returns the number of rows with the given value.
+func (i *Index) Count(value string) int64 {
+	b, ok := i.bitmaps[value]
+	if !ok {
+		return 0
+	}
+	return b.Cardinality()
+}
+
+// Write writes the index to w as the number of values followed, for every
+// value in order, by its length, its bytes, the number of rows with it and
+// its bitmap, in the format of Bitmap.Write. It returns the number of bytes
+// written.
+func (i *Index) Write(w io.Writer, order binary.ByteOrder) (int64, error) {
+	var buf [8]byte
+	order.PutUint32(buf[:4], uint32(len(i.bitmaps)))
+	if _, err := w.Write(buf[:4]); err != nil {
+		return 0, fmt.Errorf("eqindex: can't write number of values: %s", err)
+	}
+	written := int64(4)
+
+	for _, v := range i.Values() {
+		order.PutUint32(buf[:4], uint32(len(v)))
+		if _, err := w.Write(buf[:4]); err != nil {
+			return written, fmt.Errorf("eqindex: can't write value: %s", err)
+		}
+
+		if _, err := io.WriteString(w, v); err != nil {
+			return written, fmt.Errorf("eqindex: can't write value: %s", err)
+		}
+
+		order.PutUint64(buf[:], uint64(i.bitmaps[v].Cardinality()))
+		if _, err := w.Write(buf[:]); err != nil {
+			return written, fmt.Errorf("eqindex: can't write count of value: %s", err)
+		}
+		written += int64(4 + len(v) + 8)
+
+		n, err := i.bitmaps[v].Write(w, order)
+		written += n
+		if err != nil {
+			return written, err
+		}
+	}
+
+	return written, nil
+}
+
+// Read reads an index written by Index.Write from r. The number of rows
+// written for every value must match its bitmap.
+func Read(r io.Reader, order binary.ByteOrder) (*Index, error) {
+	var buf [8]byte
+	if _, err := io.ReadFull(r, buf[:4]); err != nil {
+		return nil, fmt.Errorf("eqindex: can't read number of values: %s", err)
+	}
+
+	i := New()
+	for n := order.Uint32(buf[:4]); n > 0; n-- {
+		if _, err := io.ReadFull(r, buf[:4]); err != nil {
+			return nil, fmt.Errorf("eqindex: can't read value: %s", err)
+		}
+
+		// the value is read as it comes, so a corrupt length can't make it
+		// allocate more than what r holds
+		size := int(order.Uint32(buf[:4]))
+		value, err := ioutil.ReadAll(io.LimitReader(r, int64(size)))
+		if err == nil && len(value) < size {
+			err = io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return nil, fmt.Errorf("eqindex: can't read value: %s", err)
+		}
+
+		if _, err := io.ReadFull(r, buf[:]); err != nil {
+			return nil, fmt.Errorf("eqindex: can't read count of value: %s", err)
+		}
+
+		b, err := ewah.FromReader(r, order)
+		if err != nil {
+			return nil, err
+		}
+
+		if count := int64(order.Uint64(buf[:])); count != b.Cardinality() {
+			return nil, fmt.Errorf("eqindex: value %q has %d rows, but its count is %d", value, b.Cardinality(), count)
+		}
+
+		if _, ok := i.bitmaps[string(value)]; ok {
+			return nil, fmt.Errorf("eqindex: value %q is repeated", value)
+		}
+		i.bitmaps[string(value)] = b
+	}
+
+	return i, nil
+}
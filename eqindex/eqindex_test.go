@@ -0,0 +1,99 @@
+package eqindex
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math/rand"
+	"sort"
+	"testing"
+
+	ewah "github.com/erizocosmico/go-ewah"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIndex(t *testing.T) {
+	require := require.New(t)
+	r := rand.New(rand.NewSource(1))
+
+	values := []string{"", "red", "green", "blue", "black"}
+	idx := New()
+	rows := map[string][]int64{}
+	for row := int64(0); row < 5000; row++ {
+		v := values[r.Intn(len(values))]
+		rows[v] = append(rows[v], row)
+		require.NoError(idx.Add(row, v))
+	}
+	require.Error(idx.Add(10, "red"))
+
+	// adding the last row of a value again has no effect
+	last := rows["red"][len(rows["red"])-1]
+	require.NoError(idx.Add(last, "red"))
+	require.NoError(idx.Add(last, "red"))
+
+	require.Equal(len(values), idx.Distinct())
+	require.Equal([]string{"", "black", "blue", "green", "red"}, idx.Values())
+	for _, v := range values {
+		require.Equal(int64(len(rows[v])), idx.Count(v))
+		require.Equal(rows[v], positions(idx.Query(v)))
+	}
+	require.Nil(positions(idx.Query("white")))
+	require.Equal(int64(0), idx.Count("white"))
+
+	expected := append(append([]int64{}, rows["red"]...), rows["blue"]...)
+	sort.Slice(expected, func(i, j int) bool { return expected[i] < expected[j] })
+	require.Equal(expected, positions(idx.QueryIn("red", "white", "blue")))
+	require.Nil(positions(idx.QueryIn()))
+
+	// results are copies
+	require.NoError(idx.Query("red").Set(10000))
+	require.Equal(rows["red"], positions(idx.Query("red")))
+
+	var buf bytes.Buffer
+	n, err := idx.Write(&buf, binary.BigEndian)
+	require.NoError(err)
+	require.Equal(int64(buf.Len()), n)
+
+	read, err := Read(bytes.NewReader(buf.Bytes()), binary.BigEndian)
+	require.NoError(err)
+	require.Equal(idx.Values(), read.Values())
+	for _, v := range values {
+		require.Equal(idx.Count(v), read.Count(v))
+		require.Equal(rows[v], positions(read.Query(v)))
+	}
+
+	for _, size := range []int{0, 3, 10, buf.Len() - 1} {
+		_, err := Read(bytes.NewReader(buf.Bytes()[:size]), binary.BigEndian)
+		require.Error(err, "size %d", size)
+	}
+
+	// counts that don't match the bitmaps
+	single := New()
+	require.NoError(single.Add(3, "a"))
+	buf.Reset()
+	_, err = single.Write(&buf, binary.BigEndian)
+	require.NoError(err)
+	tampered := buf.Bytes()
+	binary.BigEndian.PutUint64(tampered[4+4+1:], 99)
+	_, err = Read(bytes.NewReader(tampered), binary.BigEndian)
+	require.Error(err)
+
+	dup := New()
+	for j := 0; j < 3; j++ {
+		require.NoError(dup.Add(1, "a"))
+	}
+	require.Equal(int64(1), dup.Count("a"))
+	require.Equal(int64(1), dup.Query("a").Cardinality())
+
+	empty, err := Read(bytes.NewReader([]byte{0, 0, 0, 0}), binary.BigEndian)
+	require.NoError(err)
+	require.Equal(0, empty.Distinct())
+}
+
+func positions(b *ewah.Bitmap) []int64 {
+	var result []int64
+	it := b.IteratorRange(0, int64(b.Bits()))
+	for it.HasNext() {
+		result = append(result, it.Next())
+	}
+	return result
+}
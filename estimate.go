@@ -0,0 +1,85 @@
+package ewah
+
+// Estimate is a range the size of the result of an operation is known to be
+// in.
+type Estimate struct {
+	Min, Max int64
+}
+
+// EstimateAnd returns bounds of the number of bits set in the intersection
+// of a and b. Only the markers are read, never the contents of the literal
+// words, which could have any number of bits set, so it is much cheaper than
+// computing the intersection, and it can be used to decide the order of the
+// operations of a query before doing them.
+func EstimateAnd(a, b *Bitmap) Estimate {
+	return estimate(a, b, func(x, y wordKind) (min, max int64) {
+		switch {
+		case x == zeroWords || y == zeroWords:
+			return 0, 0
+		case x == oneWords && y == oneWords:
+			return 64, 64
+		default:
+			return 0, 64
+		}
+	})
+}
+
+// EstimateOr returns bounds of the number of bits set in the union of a and
+// b, only reading their markers, like EstimateAnd.
+func EstimateOr(a, b *Bitmap) Estimate {
+	return estimate(a, b, func(x, y wordKind) (min, max int64) {
+		switch {
+		case x == oneWords || y == oneWords:
+			return 64, 64
+		case x == zeroWords && y == zeroWords:
+			return 0, 0
+		default:
+			return 0, 64
+		}
+	})
+}
+
+// wordKind is what is known about a word of a bitmap from its marker alone.
+type wordKind byte
+
+const (
+	zeroWords wordKind = iota
+	oneWords
+	literalWords
+)
+
+// estimate walks the markers of a and b in lockstep, adding up the bounds
+// returned by bound for every pair of words, given what is known about them.
+func estimate(a, b *Bitmap, bound func(x, y wordKind) (min, max int64)) Estimate {
+	kind := func(it *runIterator) (wordKind, int64) {
+		switch {
+		case it.run > 0 && it.bit:
+			return oneWords, it.run
+		case it.run > 0:
+			return zeroWords, it.run
+		case it.lits > 0:
+			return literalWords, int64(it.lits)
+		default:
+			// no words left, which are zeroes from then on
+			return zeroWords, -1
+		}
+	}
+
+	var e Estimate
+	ia, ib := a.runs(), b.runs()
+	for !ia.done() || !ib.done() {
+		x, ka := kind(&ia)
+		y, kb := kind(&ib)
+		k := ka
+		if k < 0 || kb >= 0 && kb < k {
+			k = kb
+		}
+
+		min, max := bound(x, y)
+		e.Min += min * k
+		e.Max += max * k
+		ia.discard(k)
+		ib.discard(k)
+	}
+	return e
+}
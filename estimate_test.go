@@ -0,0 +1,44 @@
+package ewah
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEstimate(t *testing.T) {
+	require := require.New(t)
+	r := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 50; i++ {
+		a := fromPositions(randomPositions(r, int64(r.Intn(20000)), r.Float64())...)
+		b := NewWithBase(int64(r.Intn(5000)))
+		for _, p := range randomPositions(r, int64(r.Intn(20000)), r.Float64()) {
+			if p >= b.Base() {
+				require.NoError(b.Set(p))
+			}
+		}
+
+		and, or := and(a, b).cardinality(), or(a, b).cardinality()
+		e := EstimateAnd(a, b)
+		require.True(e.Min <= and && and <= e.Max, "%d not in %v", and, e)
+		require.Equal(e, EstimateAnd(b, a))
+
+		e = EstimateOr(a, b)
+		require.True(e.Min <= or && or <= e.Max, "%d not in %v", or, e)
+		require.Equal(e, EstimateOr(b, a))
+	}
+
+	ones := New()
+	for p := int64(0); p < 64*10; p++ {
+		require.NoError(ones.Set(p))
+	}
+	sparse := fromPositions(3, 64*5+1, 64*20)
+
+	require.Equal(Estimate{64 * 10, 64 * 10}, EstimateAnd(ones, ones))
+	require.Equal(Estimate{0, 64 * 2}, EstimateAnd(ones, sparse))
+	require.Equal(Estimate{64 * 10, 64 * 11}, EstimateOr(ones, sparse))
+	require.Equal(Estimate{}, EstimateAnd(New(), sparse))
+	require.Equal(Estimate{0, 64 * 3}, EstimateOr(New(), sparse))
+}
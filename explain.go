@@ -0,0 +1,86 @@
+package ewah
+
+import "fmt"
+
+// Explanation describes how the bit at a position is answered by the words of
+// a bitmap. It is meant for debugging encoding issues.
+type Explanation struct {
+	// Pos is the explained position.
+	Pos int64
+	// Marker is the index in the words of the marker covering the position,
+	// or -1 if no word covers it.
+	Marker int
+	// Literal is the index in the words of the literal covering the
+	// position, or -1 if it is covered by the clean run of the marker or not
+	// covered at all.
+	Literal int
+	// Offset is the position of the first bit covered by the marker.
+	Offset int64
+	// Word is the marker, or the literal if there is one, covering the
+	// position.
+	Word uint64
+	// Value is the bit at the position.
+	Value bool
+}
+
+// String returns a human readable description of the explanation.
+func (e Explanation) String() string {
+	value := 0
+	if e.Value {
+		value = 1
+	}
+
+	switch {
+	case e.Marker < 0:
+		return fmt.Sprintf("position %d: not covered by any word: %d", e.Pos, value)
+	case e.Literal < 0:
+		r := rlw(e.Word)
+		return fmt.Sprintf(
+			"position %d: clean run of marker %d (b=%t k=%d l=%d) starting at bit %d: %d",
+			e.Pos, e.Marker, r.b(), r.k(), r.l(), e.Offset, value,
+		)
+	default:
+		return fmt.Sprintf(
+			"position %d: literal %d (%#016x) of marker %d starting at bit %d, bit %d of the word: %d",
+			e.Pos, e.Literal, e.Word, e.Marker, e.Offset, (e.Pos-e.Offset)%64, value,
+		)
+	}
+}
+
+// Explain reports which word of the bitmap covers the given position and
+// the bit found there, walking the words from the start like a reader that
+// knows nothing about the bitmap would. Its result should always agree with
+// Get, so any difference points to a bug in the encoding.
+func (b *Bitmap) Explain(pos int64) Explanation {
+	e := Explanation{Pos: pos, Marker: -1, Literal: -1}
+	if pos < b.base || pos >= b.n {
+		return e
+	}
+
+	acc := b.base
+	for i := 0; i < len(b.w); {
+		r := rlw(b.w[i])
+		kb := int64(r.k()) * 64
+		if pos < acc+kb {
+			e.Marker, e.Offset, e.Word, e.Value = i, acc, b.w[i], r.b()
+			return e
+		}
+
+		l := int64(r.l())
+		if pos < acc+kb+l*64 {
+			j := i + 1 + int((pos-acc-kb)/64)
+			if j >= len(b.w) {
+				return e
+			}
+
+			e.Marker, e.Literal, e.Offset, e.Word = i, j, acc, b.w[j]
+			e.Value = b.w[j]&(bmask>>uint64((pos-acc)%64)) != 0
+			return e
+		}
+
+		acc += kb + l*64
+		i += int(l) + 1
+	}
+
+	return e
+}
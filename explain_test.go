@@ -0,0 +1,49 @@
+package ewah
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExplain(t *testing.T) {
+	require := require.New(t)
+
+	b := New()
+	for _, p := range []int64{0, 5, 64 * 3, 64*4 + 1} {
+		require.NoError(b.Set(p))
+	}
+
+	e := b.Explain(5)
+	require.Equal(Explanation{Pos: 5, Marker: 0, Literal: 1, Offset: 0, Word: b.w[1], Value: true}, e)
+	require.Equal("position 5: literal 1 (0x8400000000000000) of marker 0 starting at bit 0, bit 5 of the word: 1", e.String())
+
+	e = b.Explain(64*2 + 3)
+	require.Equal(Explanation{Pos: 64*2 + 3, Marker: 2, Literal: -1, Offset: 64, Word: b.w[2], Value: false}, e)
+	require.Equal("position 131: clean run of marker 2 (b=false k=2 l=2) starting at bit 64: 0", e.String())
+
+	e = b.Explain(64*4 + 1)
+	require.Equal(4, e.Literal)
+	require.True(e.Value)
+
+	e = b.Explain(1000)
+	require.Equal(-1, e.Marker)
+	require.Equal("position 1000: not covered by any word: 0", e.String())
+}
+
+func TestExplainAgreesWithGet(t *testing.T) {
+	require := require.New(t)
+	r := rand.New(rand.NewSource(1))
+
+	b := NewWithBase(640)
+	for _, p := range randomPositions(r, 5000, 0.3) {
+		if p >= 640 {
+			require.NoError(b.Set(p))
+		}
+	}
+
+	for pos := int64(0); pos < 5100; pos++ {
+		require.Equal(b.Get(pos), b.Explain(pos).Value, "position %d", pos)
+	}
+}
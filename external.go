@@ -0,0 +1,200 @@
+package ewah
+
+import (
+	"bufio"
+	"container/heap"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+)
+
+var errBuilderClosed = errors.New("bitmap: external builder is closed")
+
+// ExternalBuilder builds bitmaps from more positions than fit in memory.
+// Positions can be added in any order, and are kept in a buffer that, once
+// full, is sorted and spilled to a temporary file. Write merges all the
+// files, streaming the result without ever holding the whole bitmap or
+// all the positions in memory.
+type ExternalBuilder struct {
+	dir   string
+	limit int
+	buf   []int64
+	files []*os.File
+	err   error
+}
+
+// NewExternalBuilder creates a builder that keeps up to limit positions in
+// memory and creates its temporary files in dir, or in the default
+// directory for temporary files if dir is empty. Close must be called to
+// remove the files once the builder is not needed anymore.
+func NewExternalBuilder(dir string, limit int) *ExternalBuilder {
+	if limit < 1 {
+		limit = 1
+	}
+	return &ExternalBuilder{dir: dir, limit: limit}
+}
+
+// Add sets to 1 the bit at the given position, which can be added in any
+// order and more than once.
+func (e *ExternalBuilder) Add(pos int64) error {
+	if e.err != nil {
+		return e.err
+	}
+
+	if pos < 0 {
+		return fmt.Errorf("bitmap: can't add negative position %d", pos)
+	}
+
+	e.buf = append(e.buf, pos)
+	if len(e.buf) >= e.limit {
+		e.spill()
+	}
+	return e.err
+}
+
+// spill sorts the buffered positions and writes them to a new temporary
+// file as the varint encoded differences between them, leaving out
+// duplicates.
+func (e *ExternalBuilder) spill() {
+	f, err := ioutil.TempFile(e.dir, "ewah-*")
+	if err != nil {
+		e.err = fmt.Errorf("bitmap: can't create temporary file: %s", err)
+		return
+	}
+	e.files = append(e.files, f)
+
+	sort.Slice(e.buf, func(i, j int) bool { return e.buf[i] < e.buf[j] })
+	w := bufio.NewWriter(f)
+	var tmp [binary.MaxVarintLen64]byte
+	prev := int64(-1)
+	for _, pos := range e.buf {
+		if pos == prev {
+			continue
+		}
+
+		if _, err := w.Write(tmp[:binary.PutUvarint(tmp[:], uint64(pos-prev))]); err != nil {
+			e.err = fmt.Errorf("bitmap: can't write temporary file: %s", err)
+			return
+		}
+		prev = pos
+	}
+
+	if err := w.Flush(); err != nil {
+		e.err = fmt.Errorf("bitmap: can't write temporary file: %s", err)
+		return
+	}
+	e.buf = e.buf[:0]
+}
+
+// Write writes the bitmap with all the positions added so far to w at its
+// current offset, in the same format as Bitmap.Write, so it can be read with
+// FromReader.
+func (e *ExternalBuilder) Write(w io.WriteSeeker, order binary.ByteOrder) error {
+	if e.err != nil {
+		return e.err
+	}
+
+	if len(e.buf) > 0 {
+		if e.spill(); e.err != nil {
+			return e.err
+		}
+	}
+
+	var chunks chunkHeap
+	for _, f := range e.files {
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return fmt.Errorf("bitmap: can't read temporary file: %s", err)
+		}
+
+		c := &chunkReader{r: bufio.NewReader(f), pos: -1}
+		if c.next() {
+			chunks = append(chunks, c)
+		} else if c.err != nil {
+			return c.err
+		}
+	}
+	heap.Init(&chunks)
+
+	s, err := NewStreamWriter(w, order)
+	if err != nil {
+		return err
+	}
+
+	prev := int64(-1)
+	for len(chunks) > 0 {
+		c := chunks[0]
+		if c.pos != prev {
+			if err := s.Set(c.pos); err != nil {
+				return err
+			}
+			prev = c.pos
+		}
+
+		if c.next() {
+			heap.Fix(&chunks, 0)
+		} else if c.err != nil {
+			return c.err
+		} else {
+			heap.Pop(&chunks)
+		}
+	}
+
+	return s.Close()
+}
+
+// Close removes the temporary files of the builder, which can't be used
+// after closing it.
+func (e *ExternalBuilder) Close() error {
+	var err error
+	for _, f := range e.files {
+		f.Close()
+		if rmErr := os.Remove(f.Name()); rmErr != nil && err == nil {
+			err = fmt.Errorf("bitmap: can't remove temporary file: %s", rmErr)
+		}
+	}
+
+	e.files, e.buf, e.err = nil, nil, errBuilderClosed
+	return err
+}
+
+// chunkReader reads the positions of a file spilled by an ExternalBuilder.
+type chunkReader struct {
+	r *bufio.Reader
+	// pos is the last position read.
+	pos int64
+	err error
+}
+
+// next reads the next position, reporting whether there was one.
+func (c *chunkReader) next() bool {
+	delta, err := binary.ReadUvarint(c.r)
+	if err != nil {
+		if err != io.EOF {
+			c.err = fmt.Errorf("bitmap: can't read temporary file: %s", err)
+		}
+		return false
+	}
+
+	c.pos += int64(delta)
+	return true
+}
+
+// chunkHeap is a min-heap of chunk readers, whose first element is the one
+// with the lowest position.
+type chunkHeap []*chunkReader
+
+func (h chunkHeap) Len() int            { return len(h) }
+func (h chunkHeap) Less(i, j int) bool  { return h[i].pos < h[j].pos }
+func (h chunkHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *chunkHeap) Push(x interface{}) { *h = append(*h, x.(*chunkReader)) }
+
+func (h *chunkHeap) Pop() interface{} {
+	old := *h
+	c := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return c
+}
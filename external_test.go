@@ -0,0 +1,76 @@
+package ewah
+
+import (
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExternalBuilder(t *testing.T) {
+	require := require.New(t)
+	r := rand.New(rand.NewSource(1))
+
+	dir, err := ioutil.TempDir("", "ewah")
+	require.NoError(err)
+	defer os.RemoveAll(dir)
+
+	ps := randomPositions(r, 200000, 0.3)
+	added := append([]int64{}, ps...)
+	// duplicates, even across spilled files
+	added = append(added, ps[:1000]...)
+	r.Shuffle(len(added), func(i, j int) { added[i], added[j] = added[j], added[i] })
+
+	e := NewExternalBuilder(dir, 5000)
+	for _, p := range added {
+		require.NoError(e.Add(p))
+	}
+	require.Error(e.Add(-1))
+
+	files, err := filepath.Glob(filepath.Join(dir, "ewah-*"))
+	require.NoError(err)
+	require.True(len(files) > 1)
+
+	f, err := ioutil.TempFile(dir, "out")
+	require.NoError(err)
+	defer f.Close()
+
+	require.NoError(e.Write(f, binary.LittleEndian))
+	_, err = f.Seek(0, io.SeekStart)
+	require.NoError(err)
+
+	b, err := FromReader(f, binary.LittleEndian)
+	require.NoError(err)
+	require.Equal(fromPositions(ps...).w, b.w)
+	require.Equal(ps, positions(b))
+
+	require.NoError(e.Close())
+	files, err = filepath.Glob(filepath.Join(dir, "ewah-*"))
+	require.NoError(err)
+	require.Empty(files)
+	require.Equal(errBuilderClosed, e.Add(1))
+}
+
+func TestExternalBuilderEmpty(t *testing.T) {
+	require := require.New(t)
+
+	f, err := ioutil.TempFile("", "ewah")
+	require.NoError(err)
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	e := NewExternalBuilder("", 10)
+	defer e.Close()
+	require.NoError(e.Write(f, binary.BigEndian))
+
+	_, err = f.Seek(0, io.SeekStart)
+	require.NoError(err)
+	b, err := FromReader(f, binary.BigEndian)
+	require.NoError(err)
+	require.Equal(int64(0), b.n)
+}
@@ -0,0 +1,100 @@
+package ewah
+
+import "sort"
+
+// FastAnd returns a new bitmap with the intersection of all the given
+// bitmaps, computed in a single pass over all of them at once, instead of
+// intersecting them in pairs. The bitmaps are looked at from the smallest to
+// the largest once compressed, so a run of zeroes in any of them skips the
+// words it covers in all the rest, and the pass stops as soon as one of them
+// has nothing but zeroes left, as nothing else can be set, which is right
+// away if any of them is empty. The result has as many bits as the largest
+// of them.
+func FastAnd(bitmaps ...*Bitmap) *Bitmap {
+	out := New()
+	var n int64
+	for _, b := range bitmaps {
+		n = maxInt64(n, b.n)
+	}
+
+	sorted := make([]*Bitmap, len(bitmaps))
+	copy(sorted, bitmaps)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return len(sorted[i].w) < len(sorted[j].w)
+	})
+
+	its := make([]runIterator, len(sorted))
+	for i, b := range sorted {
+		its[i] = b.runs()
+	}
+
+	var pos int64
+	for len(its) > 0 && allRemaining(its) {
+		var zeroes int64
+		for i := range its {
+			if its[i].run > 0 && !its[i].bit {
+				zeroes = maxInt64(zeroes, its[i].run)
+			}
+		}
+
+		if zeroes > 0 {
+			out.appendClean(false, zeroes)
+			discardAll(its, zeroes)
+			pos += zeroes
+			continue
+		}
+
+		k, lits := segmentLen(&its[0]), its[0].run == 0
+		for i := range its[1:] {
+			k = minInt64(k, segmentLen(&its[i+1]))
+			lits = lits || its[i+1].run == 0
+		}
+
+		if lits {
+			for j := 0; j < int(k); j++ {
+				word := allones
+				for i := range its {
+					if its[i].run == 0 {
+						word &= its[i].literal(j)
+					}
+				}
+				out.appendLiteral(word)
+			}
+		} else {
+			out.appendClean(true, k)
+		}
+		discardAll(its, k)
+		pos += k
+	}
+
+	out.appendClean(false, wordsFor(n)-pos)
+	out.n = n
+	out.assertInvariants()
+	return out
+}
+
+// allRemaining reports whether all the iterators have set bits left.
+func allRemaining(its []runIterator) bool {
+	for i := range its {
+		if its[i].onlyZeroes() {
+			return false
+		}
+	}
+	return true
+}
+
+// discardAll skips the next n words of all the iterators.
+func discardAll(its []runIterator, n int64) {
+	for i := range its {
+		its[i].discard(n)
+	}
+}
+
+// segmentLen returns the number of words in the current run or literals of
+// the iterator.
+func segmentLen(it *runIterator) int64 {
+	if it.run > 0 {
+		return it.run
+	}
+	return int64(it.lits)
+}
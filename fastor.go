@@ -0,0 +1,119 @@
+package ewah
+
+import "container/heap"
+
+// FastOr returns a new bitmap with the union of all the given bitmaps,
+// computed in a single pass over all of them at once, instead of merging
+// them in pairs. The bitmaps are kept in a heap by the position of their
+// next word that is not zero, so runs of zeroes are skipped without
+// looking at them, and a run of ones skips the words of all the others it
+// covers. The result has as many bits as the largest of them.
+func FastOr(bitmaps ...*Bitmap) *Bitmap {
+	out := New()
+	var n int64
+	var cursors orHeap
+	for _, b := range bitmaps {
+		n = maxInt64(n, b.n)
+		c := &orCursor{it: b.runs()}
+		if c.skipZeroes() {
+			cursors = append(cursors, c)
+		}
+	}
+	heap.Init(&cursors)
+
+	var pos int64
+	var popped []*orCursor
+	for len(cursors) > 0 {
+		if off := cursors[0].off; off > pos {
+			out.appendClean(false, off-pos)
+			pos = off
+		}
+
+		popped = popped[:0]
+		var ones int64
+		for len(cursors) > 0 && cursors[0].off == pos {
+			c := heap.Pop(&cursors).(*orCursor)
+			if c.it.run > 0 {
+				ones = maxInt64(ones, c.it.run)
+			}
+			popped = append(popped, c)
+		}
+
+		if ones > 0 {
+			// every word the run covers is set, whatever the rest have
+			for len(cursors) > 0 && cursors[0].off < pos+ones {
+				popped = append(popped, heap.Pop(&cursors).(*orCursor))
+			}
+
+			out.appendClean(true, ones)
+			pos += ones
+		} else {
+			k := int64(popped[0].it.lits)
+			for _, c := range popped[1:] {
+				k = minInt64(k, int64(c.it.lits))
+			}
+			if len(cursors) > 0 {
+				k = minInt64(k, cursors[0].off-pos)
+			}
+
+			for i := 0; i < int(k); i++ {
+				var word uint64
+				for _, c := range popped {
+					word |= c.it.literal(i)
+				}
+				out.appendLiteral(word)
+			}
+			pos += k
+		}
+
+		for _, c := range popped {
+			c.discard(pos - c.off)
+			if c.skipZeroes() {
+				heap.Push(&cursors, c)
+			}
+		}
+	}
+
+	out.appendClean(false, wordsFor(n)-pos)
+	out.n = n
+	out.assertInvariants()
+	return out
+}
+
+// orCursor is the state of a bitmap in FastOr.
+type orCursor struct {
+	it runIterator
+	// off is the index of the next word of the iterator.
+	off int64
+}
+
+// discard skips the next n words.
+func (c *orCursor) discard(n int64) {
+	c.it.discard(n)
+	c.off += n
+}
+
+// skipZeroes skips the runs of zeroes, reporting whether there are words
+// left.
+func (c *orCursor) skipZeroes() bool {
+	for c.it.run > 0 && !c.it.bit {
+		c.discard(c.it.run)
+	}
+	return !c.it.done()
+}
+
+// orHeap is a min-heap of cursors, whose first element is the one with the
+// lowest offset.
+type orHeap []*orCursor
+
+func (h orHeap) Len() int            { return len(h) }
+func (h orHeap) Less(i, j int) bool  { return h[i].off < h[j].off }
+func (h orHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *orHeap) Push(x interface{}) { *h = append(*h, x.(*orCursor)) }
+
+func (h *orHeap) Pop() interface{} {
+	old := *h
+	c := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return c
+}
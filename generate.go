@@ -0,0 +1,58 @@
+package ewah
+
+import (
+	"math"
+	"math/rand"
+)
+
+// GenerateOptions are the parameters of the bitmaps created by Generate.
+type GenerateOptions struct {
+	// Bits is the number of bits of the bitmap.
+	Bits int64
+	// Density is the expected fraction of set bits, from 0 to 1.
+	Density float64
+	// MeanRun is the expected length of the runs of ones. The lengths follow
+	// a geometric distribution, so 1 or less gives independent bits and
+	// greater values cluster the set bits in longer runs.
+	MeanRun float64
+	// Seed is the seed of the random generator. The same options always
+	// produce the same bitmap.
+	Seed int64
+}
+
+// Generate creates a random bitmap with the given options, made of runs of
+// ones and zeroes whose lengths are drawn so the bitmap has the expected
+// density and clustering, which is useful to benchmark and size systems
+// with realistic inputs. Runs are encoded as they are drawn, so even large
+// bitmaps are cheap to generate as long as runs are long.
+func Generate(opts GenerateOptions) *Bitmap {
+	s := newStreamBuilder()
+	density := math.Max(0, math.Min(opts.Density, 1))
+	switch density {
+	case 0, 1:
+		s.addRun(density == 1, opts.Bits)
+		return s.bitmap()
+	}
+
+	r := rand.New(rand.NewSource(opts.Seed))
+	meanOnes := math.Max(opts.MeanRun, 1)
+	meanZeroes := meanOnes * (1 - density) / density
+	for s.n < opts.Bits {
+		// zeroes are drawn from 0, so runs of ones can be next to each other
+		// and high densities are possible even with short runs
+		zeroes := geometric(r, 1/(1+meanZeroes))
+		s.addRun(false, minInt64(zeroes, opts.Bits-s.n))
+		ones := 1 + geometric(r, 1/meanOnes)
+		s.addRun(true, minInt64(ones, opts.Bits-s.n))
+	}
+	return s.bitmap()
+}
+
+// geometric returns the number of failures before the first success of
+// independent trials with probability p of success.
+func geometric(r *rand.Rand, p float64) int64 {
+	if p >= 1 {
+		return 0
+	}
+	return int64(r.ExpFloat64() / -math.Log1p(-p))
+}
@@ -0,0 +1,42 @@
+package ewah
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerate(t *testing.T) {
+	require := require.New(t)
+
+	const n = 1000000
+	for _, density := range []float64{0.001, 0.1, 0.5, 0.9} {
+		for _, meanRun := range []float64{1, 10, 1000} {
+			opts := GenerateOptions{Bits: n, Density: density, MeanRun: meanRun, Seed: 42}
+			b := Generate(opts)
+			require.Equal(int64(n), b.n)
+			require.InDelta(density, float64(b.cardinality())/n, 0.05+density*0.1)
+			require.Equal(b.w, Generate(opts).w)
+
+			// count the runs of ones to check their mean length
+			var runs, prev int64 = 0, -2
+			b.each(func(pos int64) bool {
+				if pos != prev+1 {
+					runs++
+				}
+				prev = pos
+				return true
+			})
+			if density <= 0.1 && n*density/meanRun >= 100 {
+				require.InEpsilon(meanRun, float64(b.cardinality())/float64(runs), 0.3, "density %v, mean run %v", density, meanRun)
+			}
+		}
+	}
+
+	require.Equal(int64(0), Generate(GenerateOptions{Bits: 100}).cardinality())
+	require.Equal(int64(100), Generate(GenerateOptions{Bits: 100, Density: 2}).cardinality())
+	require.NotEqual(
+		Generate(GenerateOptions{Bits: 10000, Density: 0.5, Seed: 1}).w,
+		Generate(GenerateOptions{Bits: 10000, Density: 0.5, Seed: 2}).w,
+	)
+}
@@ -0,0 +1,76 @@
+package ewah
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// HTTPReaderAt is an io.ReaderAt that reads the object at URL using HTTP range
+// requests, such as a bitmap stored in S3 or GCS. Together with LazyReader,
+// it allows querying a remote bitmap without downloading all of it.
+type HTTPReaderAt struct {
+	// Client is the client used to make requests. http.DefaultClient is used
+	// if it is nil.
+	Client *http.Client
+	// URL is the location of the object.
+	URL string
+}
+
+func (h *HTTPReaderAt) client() *http.Client {
+	if h.Client == nil {
+		return http.DefaultClient
+	}
+	return h.Client
+}
+
+// ReadAt reads len(p) bytes starting at offset off.
+func (h *HTTPReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	req, err := http.NewRequest(http.MethodGet, h.URL, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", off, off+int64(len(p))-1))
+
+	resp, err := h.client().Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+	case http.StatusRequestedRangeNotSatisfiable:
+		return 0, io.EOF
+	default:
+		return 0, fmt.Errorf("bitmap: unexpected status %q for range request", resp.Status)
+	}
+
+	n, err := io.ReadFull(resp.Body, p)
+	if err == io.ErrUnexpectedEOF {
+		err = io.EOF
+	}
+	return n, err
+}
+
+// Size returns the size in bytes of the object, as reported by a HEAD request.
+func (h *HTTPReaderAt) Size() (int64, error) {
+	resp, err := h.client().Head(h.URL)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("bitmap: unexpected status %q for HEAD request", resp.Status)
+	}
+
+	if resp.ContentLength < 0 {
+		return 0, fmt.Errorf("bitmap: unknown size for %s", h.URL)
+	}
+	return resp.ContentLength, nil
+}
@@ -0,0 +1,68 @@
+package ewah
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPReaderAt(t *testing.T) {
+	require := require.New(t)
+
+	r := rand.New(rand.NewSource(1))
+	b := New()
+	for i := int64(0); i < 1000000; i++ {
+		if r.Intn(2) == 0 {
+			require.NoError(b.Set(i))
+		}
+	}
+
+	buf := bytes.NewBuffer(nil)
+	_, err := b.WriteIndexed(buf, binary.BigEndian, 0)
+	require.NoError(err)
+	data := buf.Bytes()
+
+	var served int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rw := &countingResponseWriter{ResponseWriter: w}
+		http.ServeContent(rw, r, "bitmap", time.Time{}, bytes.NewReader(data))
+		served += rw.n
+	}))
+	defer srv.Close()
+
+	h := &HTTPReaderAt{URL: srv.URL}
+	size, err := h.Size()
+	require.NoError(err)
+	require.Equal(int64(len(data)), size)
+
+	l, err := NewLazyReader(h, size, binary.BigEndian)
+	require.NoError(err)
+
+	for _, pos := range []int64{0, 5000, 500000, 999999} {
+		v, err := l.Get(pos)
+		require.NoError(err)
+		require.Equal(b.Get(pos), v)
+	}
+
+	require.True(served < int64(len(data))/4, "served %d of %d bytes", served, len(data))
+
+	_, err = h.ReadAt(make([]byte, 8), size+100)
+	require.Error(err)
+}
+
+type countingResponseWriter struct {
+	http.ResponseWriter
+	n int64
+}
+
+func (w *countingResponseWriter) Write(p []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(p)
+	w.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,33 @@
+package ewah
+
+// AndInPlace makes the bitmap the intersection of itself and other.
+func (b *Bitmap) AndInPlace(other *Bitmap) error {
+	return b.mergeInPlace(other, opAnd)
+}
+
+// OrInPlace makes the bitmap the union of itself and other.
+func (b *Bitmap) OrInPlace(other *Bitmap) error {
+	return b.mergeInPlace(other, opOr)
+}
+
+// XorInPlace makes the bitmap the symmetric difference of itself and other.
+func (b *Bitmap) XorInPlace(other *Bitmap) error {
+	return b.mergeInPlace(other, opXor)
+}
+
+// AndNotInPlace unsets the bits of the bitmap that are set in other.
+func (b *Bitmap) AndNotInPlace(other *Bitmap) error {
+	return b.mergeInPlace(other, opAndNot)
+}
+
+// mergeInPlace combines the bitmap with other using op, like merge does,
+// and makes the bitmap take the result, reusing its spare words as mergeTo
+// does. other may be the bitmap itself.
+func (b *Bitmap) mergeInPlace(other *Bitmap, op func(x, y uint64) uint64) error {
+	if b.frozen {
+		return ErrFrozen
+	}
+
+	mergeTo(b, b, other, op, nil)
+	return nil
+}
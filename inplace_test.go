@@ -0,0 +1,66 @@
+package ewah
+
+import (
+	"math/rand"
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestInPlaceOps(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+
+	cases := []struct {
+		name    string
+		inPlace func(b, other *Bitmap) error
+		op      func(a, b *Bitmap) *Bitmap
+	}{
+		{"AndInPlace", (*Bitmap).AndInPlace, and},
+		{"OrInPlace", (*Bitmap).OrInPlace, or},
+		{"XorInPlace", (*Bitmap).XorInPlace, xor},
+		{"AndNotInPlace", (*Bitmap).AndNotInPlace, andNot},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			require := require.New(t)
+			for i := 0; i < 20; i++ {
+				a := fromPositions(randomPositions(r, int64(r.Intn(10000)), r.Float64())...)
+				b := fromPositions(randomPositions(r, int64(r.Intn(10000)), r.Float64())...)
+
+				expected := c.op(a, b)
+				require.NoError(c.inPlace(a, b))
+				require.Equal(expected.n, a.n)
+				require.Equal(expected.w, a.w)
+				require.NoError(a.checkInvariants())
+
+				expected = c.op(a, a)
+				require.NoError(c.inPlace(a, a))
+				require.Equal(expected.w, a.w)
+			}
+
+			b := fromPositions(1, 2)
+			b.Freeze()
+			require.Equal(ErrFrozen, c.inPlace(b, New()))
+		})
+	}
+}
+
+func TestInPlaceAllocs(t *testing.T) {
+	a := fromPositions(randomPositions(rand.New(rand.NewSource(1)), 100000, 0.3)...)
+	b := fromPositions(randomPositions(rand.New(rand.NewSource(2)), 100000, 0.3)...)
+	require.NoError(t, a.OrInPlace(b))
+
+	allocs := testing.AllocsPerRun(10, func() {
+		require.NoError(t, a.AndInPlace(b))
+	})
+	require.True(t, allocs < 1, "allocs per run: %v", allocs)
+
+	// the spare words are accounted for and can be dropped
+	spare := int64(cap(a.spare)) * 8
+	require.True(t, spare > 0)
+	require.Equal(t, int64(unsafe.Sizeof(*a))+int64(cap(a.w))*8+spare, a.DeepSizeOf())
+	require.True(t, a.ShrinkToFit() >= spare)
+	require.Nil(t, a.spare)
+}
@@ -0,0 +1,70 @@
+package ewah
+
+import "sync"
+
+// Registry deduplicates bitmaps with identical contents, so that all of them
+// can share a single frozen instance. It is safe to use a Registry from
+// several goroutines at once.
+type Registry struct {
+	mu      sync.Mutex
+	bitmaps map[uint64][]*Bitmap
+	len     int
+}
+
+// NewRegistry creates a new empty registry.
+func NewRegistry() *Registry {
+	return &Registry{bitmaps: make(map[uint64][]*Bitmap)}
+}
+
+// Intern returns a frozen bitmap with the same bits and words as b. The
+// first time some contents are interned, a frozen copy of b is stored in the
+// registry and returned; after that, the same copy is returned for every
+// bitmap identical to it. b itself is never modified nor stored.
+func (r *Registry) Intern(b *Bitmap) *Bitmap {
+	h := b.hash()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, other := range r.bitmaps[h] {
+		if b.identical(other) {
+			return other
+		}
+	}
+
+	c := b.Clone()
+	c.Freeze()
+	r.bitmaps[h] = append(r.bitmaps[h], c)
+	r.len++
+	return c
+}
+
+// Len returns the number of distinct bitmaps in the registry.
+func (r *Registry) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.len
+}
+
+// hash returns a hash of the bits and words of the bitmap.
+func (b *Bitmap) hash() uint64 {
+	h := mix64(uint64(b.n) ^ mix64(uint64(b.base)))
+	for _, w := range b.w {
+		h = mix64(h ^ w)
+	}
+	return h
+}
+
+// identical reports whether both bitmaps have the same bits and words.
+func (b *Bitmap) identical(other *Bitmap) bool {
+	if b.n != other.n || b.base != other.base || len(b.w) != len(other.w) {
+		return false
+	}
+
+	for i, w := range b.w {
+		if w != other.w[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,64 @@
+package ewah
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry(t *testing.T) {
+	require := require.New(t)
+
+	r := NewRegistry()
+	a := fromPositions(1, 2, 300)
+
+	ia := r.Intern(a)
+	require.NotSame(a, ia)
+	require.True(ia.Frozen())
+	require.False(a.Frozen())
+	require.Equal(positions(a), positions(ia))
+
+	require.Same(ia, r.Intern(fromPositions(1, 2, 300)))
+	require.Same(ia, r.Intern(ia))
+	require.Equal(1, r.Len())
+
+	other := r.Intern(fromPositions(1, 2, 301))
+	require.NotSame(ia, other)
+	require.Same(other, r.Intern(fromPositions(1, 2, 301)))
+	require.Equal(2, r.Len())
+
+	empty := New()
+	empty.extend(10)
+	require.NotSame(r.Intern(New()), r.Intern(empty))
+	require.Equal(4, r.Len())
+
+	require.Equal(ErrFrozen, ia.Set(400))
+	require.NoError(a.Set(400))
+}
+
+func TestFrozenBitmapConcurrentGet(t *testing.T) {
+	require := require.New(t)
+
+	b := newBitmap()
+	expected := positions(b)
+	b.Freeze()
+
+	var wg sync.WaitGroup
+	results := make([][]int64, 8)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = positions(b)
+		}(i)
+	}
+	wg.Wait()
+
+	for _, r := range results {
+		require.Equal(expected, r)
+	}
+
+	require.Panics(func() { AndTo(b, New(), New()) })
+	require.False(b.Clone().Frozen())
+}
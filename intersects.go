@@ -0,0 +1,109 @@
+package ewah
+
+// setSegment is a range of words of a bitmap with set bits: either a run of
+// ones or a group of literal words.
+type setSegment struct {
+	// start is the index of the first word of the segment in the
+	// uncompressed bitmap, and count the number of words.
+	start, count int64
+	// lit is the index of the first literal in the encoded words, or -1 if
+	// the segment is a run of ones.
+	lit int
+}
+
+// setSegments returns all the segments of the bitmap with set bits, in
+// ascending order.
+func (b *Bitmap) setSegments() []setSegment {
+	var segments []setSegment
+	var offset int64
+	it := b.runs()
+	for !it.done() {
+		if it.run > 0 && it.bit {
+			segments = append(segments, setSegment{offset, it.run, -1})
+		}
+		offset += it.run
+
+		if it.lits > 0 {
+			segments = append(segments, setSegment{offset, int64(it.lits), it.lit})
+			offset += int64(it.lits)
+		}
+
+		it.discard(it.run + int64(it.lits))
+	}
+	return segments
+}
+
+// intersectsSegments reports whether b has a set bit in common with the
+// bitmap the given segments come from, whose words are w.
+func (b *Bitmap) intersectsSegments(segments []setSegment, w []uint64) bool {
+	var offset int64
+	j := 0
+	it := b.runs()
+	for !it.done() && j < len(segments) {
+		if it.run > 0 {
+			end := offset + it.run
+			for j < len(segments) && segments[j].start+segments[j].count <= offset {
+				j++
+			}
+
+			if it.bit && j < len(segments) && segments[j].start < end {
+				return true
+			}
+
+			offset = end
+			it.discard(it.run)
+			continue
+		}
+
+		for j < len(segments) && segments[j].start+segments[j].count <= offset {
+			j++
+		}
+
+		if j < len(segments) && segments[j].start <= offset {
+			s := segments[j]
+			if s.lit < 0 {
+				if it.literal(0) != 0 {
+					return true
+				}
+			} else if it.literal(0)&w[s.lit+int(offset-s.start)] != 0 {
+				return true
+			}
+		}
+
+		offset++
+		it.discard(1)
+	}
+
+	return false
+}
+
+// Intersects reports whether the bitmap has any set bit in common with
+// other. Both are walked in lockstep, stopping at the first common bit.
+func (b *Bitmap) Intersects(other *Bitmap) bool {
+	return mergeAny(b, other, opAnd)
+}
+
+// IntersectsAny reports, for each of the candidates, whether it has any set
+// bit in common with the bitmap. The words of the bitmap with set bits are
+// located only once and shared by all the tests, which makes it cheaper than
+// testing every candidate on its own.
+func (b *Bitmap) IntersectsAny(candidates []*Bitmap) []bool {
+	segments := b.setSegments()
+	result := make([]bool, len(candidates))
+	for i, c := range candidates {
+		result[i] = c.intersectsSegments(segments, b.w)
+	}
+	return result
+}
+
+// FirstIntersecting returns the index of the first of the candidates that
+// has any set bit in common with the bitmap, or -1 if none of them does.
+func (b *Bitmap) FirstIntersecting(candidates []*Bitmap) int {
+	segments := b.setSegments()
+	for i, c := range candidates {
+		if c.intersectsSegments(segments, b.w) {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,58 @@
+package ewah
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIntersectsAny(t *testing.T) {
+	require := require.New(t)
+
+	b := newBitmap()
+	candidates := []*Bitmap{
+		New(),
+		fromPositions(1, 2, 3),
+		fromPositions(5*64 + 58),
+		fromPositions(5*64+57, 7*64+1),
+		fromPositions(8*64 + 4),
+		fromPositions(8*64+4, 9*64+63),
+		fromPositions(10*64 + 1),
+	}
+
+	require.Equal(
+		[]bool{false, false, true, true, false, true, false},
+		b.IntersectsAny(candidates),
+	)
+	require.Equal(2, b.FirstIntersecting(candidates))
+	require.Equal(-1, b.FirstIntersecting(candidates[:2]))
+	require.Equal(-1, New().FirstIntersecting(candidates))
+
+	for i, c := range candidates {
+		require.Equal(b.IntersectsAny(candidates)[i], b.Intersects(c))
+		require.Equal(b.IntersectsAny(candidates)[i], c.Intersects(b))
+	}
+}
+
+func TestIntersectsAnyRandom(t *testing.T) {
+	require := require.New(t)
+	r := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 20; i++ {
+		b := fromPositions(randomPositions(r, 5000, r.Float64()/10)...)
+
+		var candidates []*Bitmap
+		var expected []bool
+		for j := 0; j < 20; j++ {
+			c := fromPositions(randomPositions(r, int64(r.Intn(6000)), r.Float64()/10)...)
+			candidates = append(candidates, c)
+			expected = append(expected, !and(b, c).empty())
+		}
+
+		require.Equal(expected, b.IntersectsAny(candidates))
+		for j, c := range candidates {
+			require.Equal(expected[j], b.Intersects(c))
+		}
+	}
+}
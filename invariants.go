@@ -0,0 +1,75 @@
+package ewah
+
+import "fmt"
+
+// Validate checks the structural integrity of the bitmap, which is worth
+// doing before trusting bitmaps read from disk or the network, since the
+// rest of the methods assume it and may return wrong results for corrupt
+// bitmaps. Every marker must be followed by as many literal words as it
+// claims, the markers must cover exactly the words needed to hold the bits
+// of the bitmap, the last marker must be the one new words are written to,
+// no literal word may be all zeroes or all ones, as those are always written
+// as runs, and no bit at or beyond the number of bits may be set. The first
+// problem found is returned as a *CorruptError.
+func (b *Bitmap) Validate() error {
+	return b.checkInvariants()
+}
+
+// checkInvariants checks that the encoding of the bitmap is consistent: the
+// markers account for exactly the words needed to hold its bits, no literal
+// word is clean, lastrlw and prevrlw point to the last two markers, no bit at
+// or beyond n is set and the cached number of set bits, if any, is right.
+func (b *Bitmap) checkInvariants() error {
+	if b.n < b.base {
+		return &CorruptError{-1, fmt.Sprintf("bitmap has %d bits, but its base is %d", b.n, b.base)}
+	}
+
+	last, prev := -1, -1
+	var words, lastoff int64
+	for i := 0; i < len(b.w); i += int(rlw(b.w[i]).l()) + 1 {
+		r := rlw(b.w[i])
+		if i+int(r.l()) >= len(b.w) {
+			return &CorruptError{i, fmt.Sprintf("marker has %d literals, but is followed by %d words", r.l(), len(b.w)-i-1)}
+		}
+
+		for j := i + 1; j <= i+int(r.l()); j++ {
+			if b.w[j] == 0 || b.w[j] == allones {
+				return &CorruptError{j, "literal word is clean"}
+			}
+		}
+
+		lastoff = words * 64
+		words += int64(r.k()) + int64(r.l())
+		prev, last = last, i
+	}
+
+	if last != b.lastrlw {
+		return &CorruptError{b.lastrlw, fmt.Sprintf("last marker is at word %d", last)}
+	}
+
+	if prev != b.prevrlw {
+		return &CorruptError{b.prevrlw, fmt.Sprintf("marker before the last one is at word %d", prev)}
+	}
+
+	if last >= 0 && lastoff != b.lastoff {
+		return &CorruptError{b.lastrlw, fmt.Sprintf("last marker covers bits from %d, not %d", lastoff, b.lastoff)}
+	}
+
+	if expected := wordsFor(b.n) - b.base/64; words != expected {
+		return &CorruptError{-1, fmt.Sprintf("markers cover %d words, but %d bits need %d", words, b.n, expected)}
+	}
+
+	if b.n%64 != 0 && last >= 0 {
+		r := rlw(b.w[last])
+		tail := allones >> uint64(b.n%64)
+		if r.l() > 0 && b.w[len(b.w)-1]&tail != 0 || r.l() == 0 && r.b() && r.k() > 0 {
+			return &CorruptError{len(b.w) - 1, fmt.Sprintf("bits at or beyond %d are set", b.n)}
+		}
+	}
+
+	if count := b.cardinality(); b.hasCard && count != b.card {
+		return &CorruptError{-1, fmt.Sprintf("bitmap has %d set bits, but %d are cached", count, b.card)}
+	}
+
+	return nil
+}
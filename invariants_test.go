@@ -0,0 +1,86 @@
+package ewah
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckInvariants(t *testing.T) {
+	require := require.New(t)
+	r := rand.New(rand.NewSource(1))
+
+	valid := []*Bitmap{New(), NewWithBase(640), newBitmap(), Build(1000, func(pos int64) bool { return pos%7 == 0 })}
+	for i := 0; i < 10; i++ {
+		b := fromPositions(randomPositions(r, 5000, r.Float64())...)
+		valid = append(valid, b, not(b, b.n+100), xor(b, valid[i]))
+	}
+	for i, b := range valid {
+		require.NoError(b.checkInvariants(), "bitmap %d", i)
+	}
+
+	corrupt := []*Bitmap{
+		// fewer bits than the base
+		{n: 10, base: 64, lastrlw: -1, prevrlw: -1},
+		// marker with more literals than words
+		{n: 128, w: []uint64{uint64(newRlw(false, 1, 5)), 0xff}, lastrlw: 0, prevrlw: -1},
+		// wrong last marker
+		{n: 256, w: []uint64{uint64(newRlw(true, 1, 1)), 0xf, uint64(newRlw(false, 2, 0))}, lastrlw: 0, prevrlw: -1},
+		// wrong marker before the last one
+		{n: 256, w: []uint64{uint64(newRlw(true, 1, 1)), 0xf, uint64(newRlw(false, 2, 0))}, lastrlw: 2, prevrlw: -1},
+		// wrong offset of the last marker
+		{n: 256, w: []uint64{uint64(newRlw(true, 1, 1)), 0xf, uint64(newRlw(false, 2, 0))}, lastrlw: 2, prevrlw: 0, lastoff: 64},
+		// too few words for the bits
+		{n: 300, w: []uint64{uint64(newRlw(true, 1, 1)), 0xf}, lastrlw: 0, prevrlw: -1},
+		// bits set beyond n
+		{n: 100, w: []uint64{uint64(newRlw(true, 1, 1)), 0xf}, lastrlw: 0, prevrlw: -1},
+		{n: 100, w: []uint64{uint64(newRlw(true, 2, 0))}, lastrlw: 0, prevrlw: -1},
+		// clean literal words
+		{n: 128, w: []uint64{uint64(newRlw(true, 1, 1)), 0}, lastrlw: 0, prevrlw: -1},
+		{n: 128, w: []uint64{uint64(newRlw(false, 0, 2)), 0xf, allones}, lastrlw: 0, prevrlw: -1},
+	}
+	for i, b := range corrupt {
+		var err *CorruptError
+		require.True(errors.As(b.checkInvariants(), &err), "bitmap %d", i)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	require := require.New(t)
+	r := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 10; i++ {
+		b := fromPositions(randomPositions(r, 5000, r.Float64())...)
+		var buf bytes.Buffer
+		_, err := b.Write(&buf, binary.BigEndian)
+		require.NoError(err)
+
+		read, err := FromBytes(buf.Bytes(), binary.BigEndian)
+		require.NoError(err)
+		require.NoError(read.Validate())
+	}
+
+	corrupt := [][]byte{
+		// marker with more literals than words
+		{0, 0, 0, 0x80, 0, 0, 0, 0x01, 0, 0, 0, 0, 0, 0, 0, 0x05, 0, 0, 0, 0},
+		// words that cover fewer bits than the bitmap has
+		{0, 0, 0x03, 0xe8, 0, 0, 0, 0x01, 0, 0, 0, 0, 0x80, 0, 0, 0, 0, 0, 0, 0},
+		// literal word with all its bits unset
+		{0, 0, 0, 0x40, 0, 0, 0, 0x02, 0, 0, 0, 0, 0, 0, 0, 0x01, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0},
+	}
+	for i, data := range corrupt {
+		b, err := FromBytes(data, binary.BigEndian)
+		require.NoError(err, "bitmap %d", i)
+
+		var cerr *CorruptError
+		require.True(errors.As(b.Validate(), &cerr), "bitmap %d", i)
+	}
+
+	b := fromPositions(1, 2, 3)
+	b.Freeze()
+	require.NoError(b.Validate())
+}
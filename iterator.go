@@ -0,0 +1,100 @@
+package ewah
+
+import "math"
+
+// Iterator walks the positions of the set bits of a bitmap in ascending
+// order. The bitmap must not be modified while it is being iterated.
+type Iterator struct {
+	bits *bitIterator
+	// to is the position at which the iteration stops.
+	to int64
+	// offset is subtracted from every position returned.
+	offset int64
+
+	next    int64
+	has     bool
+	fetched bool
+}
+
+// IteratorRange returns an iterator over the positions of the set bits in
+// the interval [from, to). The iterator jumps straight to the first word
+// covering from, skipping whole runs and literal words, and stops as soon as
+// it reaches to.
+func (b *Bitmap) IteratorRange(from, to int64) *Iterator {
+	bits := b.bits()
+	if from > 0 {
+		bits.seek(from)
+	}
+	return &Iterator{bits: bits, to: to}
+}
+
+// Iterator returns an iterator over the positions of all the set bits of the
+// bitmap. Runs of ones are walked without decoding their words, and the set
+// bits of literal words are found by counting their leading zeroes, so the
+// unset bits are never looked at one by one.
+func (b *Bitmap) Iterator() *Iterator {
+	return &Iterator{bits: b.bits(), to: math.MaxInt64}
+}
+
+// ForEach calls fn with the position of every set bit in ascending order,
+// stopping as soon as fn returns false. It needs no iterator, and runs of
+// ones are walked without decoding their words.
+func (b *Bitmap) ForEach(fn func(pos int64) bool) {
+	b.each(fn)
+}
+
+// HasNext reports whether there are positions left.
+func (it *Iterator) HasNext() bool {
+	if !it.fetched {
+		it.next, it.has = it.bits.next()
+		if it.next >= it.to {
+			it.has = false
+		}
+		it.fetched = true
+	}
+	return it.has
+}
+
+// Next returns the next position, or -1 if there are none left.
+func (it *Iterator) Next() int64 {
+	if !it.HasNext() {
+		return -1
+	}
+
+	it.fetched = false
+	return it.next - it.offset
+}
+
+// AdvanceIfNeeded skips all the positions lower than minPos, jumping over
+// whole runs and literal words, so the next position returned, if any, is
+// not lower than it. Positions already at or beyond minPos are not skipped.
+func (it *Iterator) AdvanceIfNeeded(minPos int64) {
+	minPos += it.offset
+	if it.fetched {
+		if !it.has || it.next >= minPos {
+			return
+		}
+		it.fetched = false
+	}
+	it.bits.seek(minPos)
+}
+
+// NextMany fills buf with the next positions and returns how many it got,
+// which is lower than the length of buf only if there are none left. The
+// positions in runs of ones are added without going through Next one by one.
+func (it *Iterator) NextMany(buf []int64) int {
+	var n int
+	for n < len(buf) && it.HasNext() {
+		buf[n] = it.Next()
+		n++
+
+		// the rest of the current run of ones, if any
+		b := it.bits
+		end := minInt64(b.end, it.to)
+		for ; b.pos < end && n < len(buf); b.pos++ {
+			buf[n] = b.pos - it.offset
+			n++
+		}
+	}
+	return n
+}
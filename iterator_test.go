@@ -0,0 +1,206 @@
+package ewah
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIteratorRange(t *testing.T) {
+	require := require.New(t)
+
+	b := newBitmap()
+	all := positions(b)
+
+	testCases := []struct {
+		from, to int64
+	}{
+		{0, 0},
+		{0, 10 * 64},
+		{-5, 1000},
+		{5*64 + 58, 5*64 + 59},
+		{5*64 + 59, 7*64 + 3},
+		{7*64 + 3, 8*64 + 7},
+		{9*64 + 60, 20 * 64},
+		{10 * 64, 20 * 64},
+	}
+
+	for _, tt := range testCases {
+		var expected []int64
+		for _, p := range all {
+			if p >= tt.from && p < tt.to {
+				expected = append(expected, p)
+			}
+		}
+
+		require.Equal(expected, iterate(b.IteratorRange(tt.from, tt.to)), "[%d, %d)", tt.from, tt.to)
+	}
+}
+
+func TestIteratorRangeRandom(t *testing.T) {
+	require := require.New(t)
+	r := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 50; i++ {
+		ps := randomPositions(r, 10000, r.Float64())
+		b := fromPositions(ps...)
+
+		from := int64(r.Intn(11000))
+		to := from + int64(r.Intn(3000))
+
+		var expected []int64
+		for _, p := range ps {
+			if p >= from && p < to {
+				expected = append(expected, p)
+			}
+		}
+		require.Equal(expected, iterate(b.IteratorRange(from, to)))
+	}
+}
+
+func TestIterator(t *testing.T) {
+	require := require.New(t)
+	r := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 20; i++ {
+		ps := randomPositions(r, int64(r.Intn(10000)), r.Float64())
+		b := fromPositions(ps...)
+		if len(ps) == 0 {
+			require.False(b.Iterator().HasNext())
+			continue
+		}
+		require.Equal(ps, iterate(b.Iterator()))
+	}
+
+	require.Len(iterate(zeroes(1000).Not().Iterator()), 1000)
+	require.Equal([]int64{6400, 6500}, iterate(fromPositions(6400, 6500).Iterator()))
+}
+
+func TestIteratorNextMany(t *testing.T) {
+	require := require.New(t)
+	r := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 20; i++ {
+		ps := randomPositions(r, int64(r.Intn(10000)), r.Float64())
+		b := fromPositions(ps...)
+		from := int64(r.Intn(5000))
+		to := from + int64(r.Intn(5000))
+		expected := iterate(b.IteratorRange(from, to))
+
+		var result []int64
+		it := b.IteratorRange(from, to)
+		buf := make([]int64, r.Intn(100)+1)
+		for {
+			if r.Intn(4) == 0 && it.HasNext() {
+				result = append(result, it.Next())
+			}
+
+			n := it.NextMany(buf)
+			result = append(result, buf[:n]...)
+			if n < len(buf) {
+				break
+			}
+		}
+		require.Equal(expected, result)
+		require.Zero(it.NextMany(buf))
+	}
+
+	it := zeroes(1000).Not().Window(10, 20).Iterator()
+	buf := make([]int64, 4)
+	require.Equal(4, it.NextMany(buf))
+	require.Equal([]int64{0, 1, 2, 3}, buf)
+	require.Equal(int64(4), it.Next())
+	require.Zero(it.NextMany(nil))
+	require.Equal(4, it.NextMany(buf))
+	require.Equal(1, it.NextMany(buf))
+	require.Equal([]int64{9}, buf[:1])
+}
+
+func TestForEach(t *testing.T) {
+	require := require.New(t)
+	r := rand.New(rand.NewSource(1))
+
+	ps := randomPositions(r, 10000, 0.5)
+	b := fromPositions(ps...)
+
+	var result []int64
+	b.ForEach(func(pos int64) bool {
+		result = append(result, pos)
+		return true
+	})
+	require.Equal(ps, result)
+
+	result = nil
+	b.ForEach(func(pos int64) bool {
+		result = append(result, pos)
+		return len(result) < 10
+	})
+	require.Equal(ps[:10], result)
+
+	var count int
+	zeroes(1000).Not().ForEach(func(pos int64) bool {
+		count++
+		return pos < 499
+	})
+	require.Equal(500, count)
+}
+
+func TestIteratorAdvanceIfNeeded(t *testing.T) {
+	require := require.New(t)
+	r := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 20; i++ {
+		ps := randomPositions(r, int64(r.Intn(20000)), r.Float64()*r.Float64())
+		it := fromPositions(ps...).Iterator()
+
+		var min int64
+		j := 0
+		for {
+			min += int64(r.Intn(300)) - 50
+			if r.Intn(2) == 0 {
+				it.HasNext()
+			}
+			it.AdvanceIfNeeded(min)
+
+			for j < len(ps) && ps[j] < min {
+				j++
+			}
+			if j == len(ps) {
+				require.False(it.HasNext())
+				break
+			}
+
+			require.Equal(ps[j], it.Next())
+			min = ps[j] + 1
+			j++
+		}
+	}
+
+	it := fromPositions(5, 100, 200).Window(50, 300).Iterator()
+	it.AdvanceIfNeeded(60)
+	require.Equal(int64(150), it.Next())
+	it.AdvanceIfNeeded(0)
+	require.False(it.HasNext())
+}
+
+func TestIteratorNext(t *testing.T) {
+	require := require.New(t)
+
+	it := fromPositions(1, 3).Iterator()
+	require.Equal(int64(1), it.Next())
+	require.True(it.HasNext())
+	require.True(it.HasNext())
+	require.Equal(int64(3), it.Next())
+	require.False(it.HasNext())
+	require.Equal(int64(-1), it.Next())
+}
+
+// iterate returns all the positions left in the iterator.
+func iterate(it *Iterator) []int64 {
+	var result []int64
+	for it.HasNext() {
+		result = append(result, it.Next())
+	}
+	return result
+}
@@ -0,0 +1,242 @@
+package ewah
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// skipIndexMagic identifies the skip index footer written by WriteIndexed.
+var skipIndexMagic = []byte("EWSI")
+
+// DefaultSkipInterval is the default number of words between entries of the
+// skip index written by WriteIndexed.
+const DefaultSkipInterval = 512
+
+// skipEntry records the position of the first bit covered by the marker at
+// the given word.
+type skipEntry struct {
+	word   int64
+	offset int64
+}
+
+// skipIndex returns an entry for the first marker at or after every multiple
+// of every words. prefix is added to the index of every word, so they point
+// to the words as written, with the base prefix before them.
+func (b *Bitmap) skipIndex(every int, prefix int64) []skipEntry {
+	var index []skipEntry
+	acc := b.base
+	next := 0
+	for i := 0; i < len(b.w); {
+		if i >= next {
+			index = append(index, skipEntry{int64(i) + prefix, acc})
+			next = (i/every + 1) * every
+		}
+
+		r := rlw(b.w[i])
+		acc += (int64(r.k()) + int64(r.l())) * 64
+		i += int(r.l()) + 1
+	}
+	return index
+}
+
+// WriteIndexed writes the bitmap like Write does, followed by a footer with a
+// skip index that has an entry every given number of words, or every
+// DefaultSkipInterval words if every is not positive. Readers that do not
+// know about the footer, such as FromReader, ignore it, while LazyReader uses
+// it to answer queries reading only a small part of the bitmap.
+func (b *Bitmap) WriteIndexed(w io.Writer, order binary.ByteOrder, every int) (int64, error) {
+	if every <= 0 {
+		every = DefaultSkipInterval
+	}
+
+	n, err := b.Write(w, order)
+	if err != nil {
+		return n, err
+	}
+
+	index := b.skipIndex(every, int64(len(b.basePrefix())))
+	for _, e := range index {
+		if err := writeUint64(w, order, uint64(e.word)); err != nil {
+			return n, err
+		}
+
+		if err := writeUint64(w, order, uint64(e.offset)); err != nil {
+			return n, err
+		}
+		n += 16
+	}
+
+	if err := writeUint32(w, order, uint32(len(index))); err != nil {
+		return n, err
+	}
+
+	if _, err := w.Write(skipIndexMagic); err != nil {
+		return n, err
+	}
+
+	return n + 8, nil
+}
+
+// LazyReader answers queries against a serialized bitmap reading only the
+// parts of it that are needed from an io.ReaderAt, such as a file or an
+// HTTPReaderAt. If the bitmap was written with WriteIndexed, point queries
+// jump straight to the right region using the skip index and usually need a
+// single read. It is safe to use a LazyReader from several goroutines as long
+// as the underlying io.ReaderAt is.
+type LazyReader struct {
+	r     io.ReaderAt
+	order binary.ByteOrder
+	n     int64
+	words int64
+	index []skipEntry
+	// window is the number of words fetched with every read.
+	window int64
+}
+
+// NewLazyReader creates a LazyReader over the size bytes of r, which must
+// contain a bitmap as written by Write or WriteIndexed. Only the header and
+// the skip index, if any, are read.
+func NewLazyReader(r io.ReaderAt, size int64, order binary.ByteOrder) (*LazyReader, error) {
+	var header [8]byte
+	if _, err := r.ReadAt(header[:], 0); err != nil {
+		return nil, fmt.Errorf("bitmap: can't read header: %s", err)
+	}
+
+	l := &LazyReader{
+		r:      r,
+		order:  order,
+		n:      int64(order.Uint32(header[:])),
+		words:  int64(order.Uint32(header[4:])),
+		window: DefaultSkipInterval,
+	}
+
+	end := 4*3 + l.words*8
+	if size < end {
+		return nil, fmt.Errorf("bitmap: need %d bytes for %d words, got %d", end, l.words, size)
+	}
+
+	if size-end >= 8 {
+		index, err := readSkipIndex(r, end, size, order)
+		if err != nil {
+			return nil, err
+		}
+		l.index = index
+	}
+
+	return l, nil
+}
+
+// readSkipIndex reads the skip index in the footer between the end of the
+// bitmap and size, if any.
+func readSkipIndex(r io.ReaderAt, end, size int64, order binary.ByteOrder) ([]skipEntry, error) {
+	var trailer [8]byte
+	if _, err := r.ReadAt(trailer[:], size-8); err != nil {
+		return nil, fmt.Errorf("bitmap: can't read skip index footer: %s", err)
+	}
+
+	if !bytes.Equal(trailer[4:], skipIndexMagic) {
+		return nil, nil
+	}
+
+	count := int64(order.Uint32(trailer[:]))
+	if end+count*16+8 != size {
+		return nil, fmt.Errorf("bitmap: skip index with %d entries does not fit in %d bytes", count, size-end)
+	}
+
+	buf := make([]byte, count*16)
+	if _, err := r.ReadAt(buf, end); err != nil {
+		return nil, fmt.Errorf("bitmap: can't read skip index: %s", err)
+	}
+
+	index := make([]skipEntry, count)
+	for i := range index {
+		index[i].word = int64(order.Uint64(buf[i*16:]))
+		index[i].offset = int64(order.Uint64(buf[i*16+8:]))
+	}
+	return index, nil
+}
+
+// Bits returns the number of uncompressed bits in the bitmap.
+func (l *LazyReader) Bits() uint32 {
+	return uint32(l.n)
+}
+
+// Get returns the bit at the given position, being true 1 and false 0.
+func (l *LazyReader) Get(pos int64) (bool, error) {
+	if pos < 0 || pos >= l.n {
+		return false, nil
+	}
+
+	var i, acc int64
+	if len(l.index) > 0 {
+		j := sort.Search(len(l.index), func(j int) bool {
+			return l.index[j].offset > pos
+		}) - 1
+		if j >= 0 {
+			i, acc = l.index[j].word, l.index[j].offset
+		}
+	}
+
+	c := lazyWindow{l: l}
+	for i < l.words {
+		word, err := c.word(i)
+		if err != nil {
+			return false, err
+		}
+
+		r := rlw(word)
+		kb := int64(r.k()) * 64
+		if pos < acc+kb {
+			return r.b(), nil
+		}
+		acc += kb
+
+		n := int64(r.l())
+		if pos < acc+n*64 {
+			j := i + 1 + (pos-acc)/64
+			if j >= l.words {
+				return false, nil
+			}
+
+			word, err := c.word(j)
+			if err != nil {
+				return false, err
+			}
+			return word&(bmask>>uint64((pos-acc)%64)) != 0, nil
+		}
+
+		acc += n * 64
+		i += n + 1
+	}
+
+	return false, nil
+}
+
+// lazyWindow keeps the last words read by a LazyReader.
+type lazyWindow struct {
+	l     *LazyReader
+	start int64
+	buf   []byte
+}
+
+// word returns the i-th word of the bitmap, reading a window of words
+// starting at i if it is not already in memory.
+func (c *lazyWindow) word(i int64) (uint64, error) {
+	if i < c.start || i >= c.start+int64(len(c.buf)/8) {
+		n := minInt64(c.l.window, c.l.words-i)
+		if int64(cap(c.buf)) < n*8 {
+			c.buf = make([]byte, n*8)
+		}
+
+		c.start, c.buf = i, c.buf[:n*8]
+		if _, err := c.l.r.ReadAt(c.buf, 8+i*8); err != nil {
+			c.buf = c.buf[:0]
+			return 0, fmt.Errorf("bitmap: can't read %dth word: %s", i+1, err)
+		}
+	}
+
+	return c.l.order.Uint64(c.buf[(i-c.start)*8:]), nil
+}
@@ -0,0 +1,89 @@
+package ewah
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLazyReader(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	ps := randomPositions(r, 200000, 0.5)
+	b := fromPositions(ps...)
+
+	for _, indexed := range []bool{false, true} {
+		require := require.New(t)
+
+		buf := bytes.NewBuffer(nil)
+		var err error
+		if indexed {
+			_, err = b.WriteIndexed(buf, binary.LittleEndian, 64)
+		} else {
+			_, err = b.Write(buf, binary.LittleEndian)
+		}
+		require.NoError(err)
+
+		reader := &countingReaderAt{r: bytes.NewReader(buf.Bytes())}
+		l, err := NewLazyReader(reader, int64(buf.Len()), binary.LittleEndian)
+		require.NoError(err)
+		require.Equal(b.Bits(), l.Bits())
+		require.Equal(indexed, len(l.index) > 0)
+
+		reader.reads = 0
+		for i := int64(0); i < b.n+10; i += 7 {
+			v, err := l.Get(i)
+			require.NoError(err)
+			require.Equal(b.Get(i), v, "%d", i)
+		}
+
+		if indexed {
+			// with the index every query takes at most a couple of reads
+			require.True(reader.reads <= int(2*(b.n+10)/7)+2, "reads: %d", reader.reads)
+		}
+	}
+}
+
+func TestWriteIndexedCompatible(t *testing.T) {
+	require := require.New(t)
+
+	b := fromPositions(randomPositions(rand.New(rand.NewSource(1)), 20000, 0.5)...)
+	buf := bytes.NewBuffer(nil)
+	n, err := b.WriteIndexed(buf, binary.BigEndian, 0)
+	require.NoError(err)
+	require.Equal(int64(buf.Len()), n)
+
+	b2, err := FromBytes(buf.Bytes(), binary.BigEndian)
+	require.NoError(err)
+	require.Equal(b, b2)
+}
+
+func TestNewLazyReaderErrors(t *testing.T) {
+	require := require.New(t)
+
+	b := fromPositions(1, 2, 3)
+	buf := bytes.NewBuffer(nil)
+	_, err := b.WriteIndexed(buf, binary.BigEndian, 0)
+	require.NoError(err)
+	data := buf.Bytes()
+
+	_, err = NewLazyReader(bytes.NewReader(data), 12, binary.BigEndian)
+	require.EqualError(err, "bitmap: need 28 bytes for 2 words, got 12")
+
+	corrupt := append([]byte{}, data...)
+	corrupt[len(corrupt)-5] = 2
+	_, err = NewLazyReader(bytes.NewReader(corrupt), int64(len(corrupt)), binary.BigEndian)
+	require.EqualError(err, "bitmap: skip index with 2 entries does not fit in 24 bytes")
+}
+
+type countingReaderAt struct {
+	r     *bytes.Reader
+	reads int
+}
+
+func (c *countingReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	c.reads++
+	return c.r.ReadAt(p, off)
+}
@@ -0,0 +1,51 @@
+package ewah
+
+// LockstepIterator walks two bitmaps together, returning every position set
+// in any of them along with which of them have it set, in ascending order.
+// It is meant for reconciling two sets, which needs the positions only in
+// a, only in b and in both, without computing the operations on their own.
+// The bitmaps must not be modified while they are being iterated.
+type LockstepIterator struct {
+	a, b *bitIterator
+	// pa and pb are the next positions of a and b, valid if hasA and hasB.
+	pa, pb     int64
+	hasA, hasB bool
+}
+
+// NewLockstepIterator returns an iterator over the positions set in a or b.
+func NewLockstepIterator(a, b *Bitmap) *LockstepIterator {
+	it := &LockstepIterator{a: a.bits(), b: b.bits()}
+	it.pa, it.hasA = it.a.next()
+	it.pb, it.hasB = it.b.next()
+	return it
+}
+
+// HasNext reports whether there are positions left.
+func (it *LockstepIterator) HasNext() bool {
+	return it.hasA || it.hasB
+}
+
+// Next returns the next position set in any of the bitmaps, and whether it
+// is set in a and in b, or -1 if there are none left.
+func (it *LockstepIterator) Next() (pos int64, inA, inB bool) {
+	switch {
+	case !it.HasNext():
+		return -1, false, false
+	case it.hasA && (!it.hasB || it.pa <= it.pb):
+		pos = it.pa
+	default:
+		pos = it.pb
+	}
+
+	if it.hasA && it.pa == pos {
+		inA = true
+		it.pa, it.hasA = it.a.next()
+	}
+
+	if it.hasB && it.pb == pos {
+		inB = true
+		it.pb, it.hasB = it.b.next()
+	}
+
+	return pos, inA, inB
+}
@@ -0,0 +1,49 @@
+package ewah
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLockstepIterator(t *testing.T) {
+	require := require.New(t)
+	r := rand.New(rand.NewSource(1))
+
+	type entry struct {
+		pos      int64
+		inA, inB bool
+	}
+
+	for i := 0; i < 30; i++ {
+		a := fromPositions(randomPositions(r, int64(r.Intn(5000)), r.Float64())...)
+		b := NewWithBase(int64(r.Intn(3000)))
+		for _, p := range randomPositions(r, int64(r.Intn(5000)), r.Float64()) {
+			if p >= b.Base() {
+				require.NoError(b.Set(p))
+			}
+		}
+
+		var expected []entry
+		for pos := int64(0); pos < 5000; pos++ {
+			if inA, inB := a.Get(pos), b.Get(pos); inA || inB {
+				expected = append(expected, entry{pos, inA, inB})
+			}
+		}
+
+		var actual []entry
+		it := NewLockstepIterator(a, b)
+		for it.HasNext() {
+			pos, inA, inB := it.Next()
+			actual = append(actual, entry{pos, inA, inB})
+		}
+		require.Equal(expected, actual)
+
+		pos, inA, inB := it.Next()
+		require.Equal(int64(-1), pos)
+		require.False(inA || inB)
+	}
+
+	require.False(NewLockstepIterator(New(), New()).HasNext())
+}
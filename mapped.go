@@ -0,0 +1,145 @@
+package ewah
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// ErrRegionFull is returned when there is no room left in the region of a
+// MappedBitmap to set a bit.
+var ErrRegionFull = errors.New("bitmap: mapped region is full")
+
+// MappedBitmap is a bitmap kept serialized, in the format of Bitmap.Write,
+// in a region of memory of fixed capacity, usually a memory-mapped file.
+// Every Set updates the words that changed and the header in the region
+// right away, so the region always holds a valid bitmap and there is no
+// need to write it at the end. A copy of the words is kept in memory to
+// read them fast. It must not be used from several goroutines at once.
+type MappedBitmap struct {
+	region []byte
+	order  binary.ByteOrder
+	b      *Bitmap
+	// unmap releases the region, if the bitmap owns it.
+	unmap func() error
+}
+
+// NewMapped creates an empty MappedBitmap in the given region, overwriting
+// its contents. The region has room for (len(region)-12)/8 words.
+func NewMapped(region []byte, order binary.ByteOrder) (*MappedBitmap, error) {
+	if len(region) < 12 {
+		return nil, fmt.Errorf("bitmap: mapped region needs at least 12 bytes, got %d", len(region))
+	}
+
+	m := &MappedBitmap{region: region, order: order, b: New()}
+	m.sync(0)
+	return m, nil
+}
+
+// OpenMapped opens the MappedBitmap serialized in the given region, so more
+// bits can be set in it.
+func OpenMapped(region []byte, order binary.ByteOrder) (*MappedBitmap, error) {
+	b, err := FromBytes(region, order)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := b.checkTail(); err != nil {
+		return nil, err
+	}
+
+	return &MappedBitmap{region: region, order: order, b: b}, nil
+}
+
+// capacity returns the number of words the region can hold.
+func (m *MappedBitmap) capacity() int {
+	return (len(m.region) - 12) / 8
+}
+
+// Set sets to 1 the bit at the given position, like Bitmap.Set, and writes
+// the change to the region. It returns ErrRegionFull, without setting the
+// bit, if the region may not have room for the words it needs.
+func (m *MappedBitmap) Set(pos int64) error {
+	b := m.b
+	if pos < b.n {
+		if pos >= b.base && b.Get(pos) {
+			return nil
+		}
+		return ErrInvalidBitSet
+	}
+
+	// the gap up to pos is added as clean words, which need a new marker
+	// every math.MaxUint32 words, and then a literal and its marker
+	gap := pos/64 - wordsFor(b.n)
+	if need := int64(len(b.w)) + 3 + maxInt64(gap, 0)/math.MaxUint32; need > int64(m.capacity()) {
+		return ErrRegionFull
+	}
+
+	dirty := b.lastrlw
+	if err := b.Set(pos); err != nil {
+		return err
+	}
+
+	// only the last marker, the one before it and the words after them can
+	// change
+	if b.lastrlw < dirty {
+		dirty = b.lastrlw
+	}
+	if b.prevrlw >= 0 && b.prevrlw < dirty {
+		dirty = b.prevrlw
+	}
+	m.sync(maxInt(dirty, 0))
+	return nil
+}
+
+// sync writes the words from the given index on and the header to the
+// region.
+func (m *MappedBitmap) sync(from int) {
+	b := m.b
+	for i := from; i < len(b.w); i++ {
+		m.order.PutUint64(m.region[8+i*8:], b.w[i])
+	}
+
+	lastrlw := uint32(b.lastrlw)
+	if b.lastrlw < 0 {
+		lastrlw = math.MaxUint32
+	}
+	m.order.PutUint32(m.region[8+len(b.w)*8:], lastrlw)
+	m.order.PutUint32(m.region, b.Bits())
+	m.order.PutUint32(m.region[4:], uint32(len(b.w)))
+}
+
+// Get returns the bit at the given position, being true 1 and false 0.
+func (m *MappedBitmap) Get(pos int64) bool {
+	return m.b.Get(pos)
+}
+
+// Bits returns the number of uncompressed bits in the bitmap.
+func (m *MappedBitmap) Bits() uint32 {
+	return m.b.Bits()
+}
+
+// Bitmap returns the bitmap held in memory, which must not be modified.
+func (m *MappedBitmap) Bitmap() *Bitmap {
+	return m.b
+}
+
+// Close releases the region if it was mapped by the MappedBitmap itself.
+// The bitmap can't be used after closing it.
+func (m *MappedBitmap) Close() error {
+	if m.unmap == nil {
+		return nil
+	}
+
+	err := m.unmap()
+	m.region, m.unmap = nil, nil
+	return err
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,54 @@
+package ewah
+
+import (
+	"encoding/binary"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMappedBitmap(t *testing.T) {
+	require := require.New(t)
+	r := rand.New(rand.NewSource(1))
+
+	ps := randomPositions(r, 20000, 0.1)
+	expected := fromPositions(ps...)
+	region := make([]byte, 12+len(expected.w)*8+100)
+
+	m, err := NewMapped(region, binary.LittleEndian)
+	require.NoError(err)
+	for i, p := range ps {
+		require.NoError(m.Set(p))
+
+		// the region holds the whole bitmap after every change
+		if i%500 == 0 || i == len(ps)-1 {
+			b, err := FromBytes(region, binary.LittleEndian)
+			require.NoError(err)
+			require.Equal(m.Bitmap().w, b.w)
+			require.Equal(m.Bitmap().lastrlw, b.lastrlw)
+			require.Equal(p+1, b.n)
+		}
+	}
+	require.Equal(ErrInvalidBitSet, m.Set(0))
+	require.Equal(expected.w, m.Bitmap().w)
+	require.True(m.Get(ps[10]))
+	require.Equal(expected.Bits(), m.Bits())
+
+	m, err = OpenMapped(region, binary.LittleEndian)
+	require.NoError(err)
+	require.Equal(ps, positions(m.Bitmap()))
+
+	// fill the region up
+	var err2 error
+	pos := m.Bitmap().n + 100
+	for err2 == nil {
+		err2 = m.Set(pos)
+		pos += 100
+	}
+	require.Equal(ErrRegionFull, err2)
+	require.True(len(m.Bitmap().w) <= m.capacity())
+
+	_, err = NewMapped(make([]byte, 11), binary.LittleEndian)
+	require.Error(err)
+}
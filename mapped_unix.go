@@ -0,0 +1,81 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+// +build darwin dragonfly freebsd linux netbsd openbsd
+
+package ewah
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// CreateMappedFile creates the file at the given path with room for
+// capacity words and maps it in memory, returning an empty MappedBitmap
+// backed by it. Close must be called to unmap the file.
+func CreateMappedFile(path string, capacity int, order binary.ByteOrder) (*MappedBitmap, error) {
+	region, err := mapFile(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 12+int64(capacity)*8)
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := NewMapped(region, order)
+	if err != nil {
+		syscall.Munmap(region)
+		return nil, err
+	}
+
+	m.unmap = func() error { return syscall.Munmap(region) }
+	return m, nil
+}
+
+// OpenMappedFile maps in memory the file at the given path, created with
+// CreateMappedFile, and returns the MappedBitmap backed by it. Close must be
+// called to unmap the file.
+func OpenMappedFile(path string, order binary.ByteOrder) (*MappedBitmap, error) {
+	region, err := mapFile(path, os.O_RDWR, -1)
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := OpenMapped(region, order)
+	if err != nil {
+		syscall.Munmap(region)
+		return nil, err
+	}
+
+	m.unmap = func() error { return syscall.Munmap(region) }
+	return m, nil
+}
+
+// mapFile opens the file at the given path with the given flags and maps it
+// in memory. If size is not negative, the file is resized to it first.
+func mapFile(path string, flag int, size int64) ([]byte, error) {
+	f, err := os.OpenFile(path, flag, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("bitmap: can't open mapped file: %s", err)
+	}
+	defer f.Close()
+
+	if size >= 0 {
+		if err := f.Truncate(size); err != nil {
+			return nil, fmt.Errorf("bitmap: can't resize mapped file: %s", err)
+		}
+	} else {
+		fi, err := f.Stat()
+		if err != nil {
+			return nil, fmt.Errorf("bitmap: can't get size of mapped file: %s", err)
+		}
+		size = fi.Size()
+	}
+
+	if size < 12 {
+		return nil, fmt.Errorf("bitmap: mapped file needs at least 12 bytes, got %d", size)
+	}
+
+	region, err := syscall.Mmap(int(f.Fd()), 0, int(size), syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, fmt.Errorf("bitmap: can't map file: %s", err)
+	}
+	return region, nil
+}
@@ -0,0 +1,47 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+// +build darwin dragonfly freebsd linux netbsd openbsd
+
+package ewah
+
+import (
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMappedFile(t *testing.T) {
+	require := require.New(t)
+
+	dir, err := ioutil.TempDir("", "ewah")
+	require.NoError(err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "bitmap")
+
+	m, err := CreateMappedFile(path, 100, binary.BigEndian)
+	require.NoError(err)
+	for _, p := range []int64{1, 2, 100, 1000, 1001} {
+		require.NoError(m.Set(p))
+	}
+	require.NoError(m.Close())
+
+	data, err := ioutil.ReadFile(path)
+	require.NoError(err)
+	b, err := FromBytes(data, binary.BigEndian)
+	require.NoError(err)
+	require.Equal([]int64{1, 2, 100, 1000, 1001}, positions(b))
+
+	m, err = OpenMappedFile(path, binary.BigEndian)
+	require.NoError(err)
+	require.NoError(m.Set(5000))
+	require.True(m.Get(1001))
+	require.NoError(m.Close())
+
+	m, err = OpenMappedFile(path, binary.BigEndian)
+	require.NoError(err)
+	require.Equal([]int64{1, 2, 100, 1000, 1001, 5000}, positions(m.Bitmap()))
+	require.NoError(m.Close())
+}
@@ -0,0 +1,64 @@
+package ewah
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrNonMonotoneMapping is returned when a mapping sends a position to a
+// position lower than the one a previous position was sent to.
+var ErrNonMonotoneMapping = errors.New("bitmap: mapping is not monotone")
+
+// ApplyMapping returns a new bitmap with the bit f(pos) set for every bit pos
+// set in the bitmap. f must be monotone, that is, for any two positions
+// a < b, f(a) <= f(b). Positions for which f returns a negative number are
+// dropped from the result.
+func (b *Bitmap) ApplyMapping(f func(pos int64) int64) (*Bitmap, error) {
+	out := New()
+	var err error
+	b.each(func(pos int64) bool {
+		np := f(pos)
+		switch {
+		case np < 0 || np == out.n-1:
+			return true
+		case np < out.n:
+			err = ErrNonMonotoneMapping
+			return false
+		}
+
+		err = out.Set(np)
+		return err == nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+// ApplyMappingSlice is like ApplyMapping, but the new position of each
+// position pos is mapping[pos]. It is an error for the bitmap to have a set
+// bit with no entry in mapping.
+func (b *Bitmap) ApplyMappingSlice(mapping []int64) (*Bitmap, error) {
+	var missing int64 = -1
+	result, err := b.ApplyMapping(func(pos int64) int64 {
+		if pos >= int64(len(mapping)) {
+			if missing < 0 {
+				missing = pos
+			}
+			return -1
+		}
+		return mapping[pos]
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if missing >= 0 {
+		return nil, fmt.Errorf("bitmap: no mapping for position %d", missing)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,55 @@
+package ewah
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyMapping(t *testing.T) {
+	require := require.New(t)
+
+	b := fromPositions(0, 1, 5, 64, 65, 66, 1000)
+
+	result, err := b.ApplyMapping(func(pos int64) int64 { return pos*2 + 10 })
+	require.NoError(err)
+	require.Equal([]int64{10, 12, 20, 138, 140, 142, 2010}, positions(result))
+
+	result, err = b.ApplyMapping(func(pos int64) int64 {
+		if pos == 5 {
+			return -1
+		}
+		return pos / 64
+	})
+	require.NoError(err)
+	require.Equal([]int64{0, 1, 15}, positions(result))
+
+	_, err = b.ApplyMapping(func(pos int64) int64 { return 100 - pos })
+	require.Equal(ErrNonMonotoneMapping, err)
+}
+
+func TestApplyMappingCleanRun(t *testing.T) {
+	require := require.New(t)
+
+	var ps []int64
+	for i := int64(64); i < 256; i++ {
+		ps = append(ps, i)
+	}
+
+	result, err := fromPositions(ps...).ApplyMapping(func(pos int64) int64 { return pos - 64 })
+	require.NoError(err)
+	require.Equal([]uint64{uint64(newRlw(true, 3, 0))}, result.w)
+}
+
+func TestApplyMappingSlice(t *testing.T) {
+	require := require.New(t)
+
+	b := fromPositions(1, 3, 4)
+
+	result, err := b.ApplyMappingSlice([]int64{-1, 0, -1, 7, 8})
+	require.NoError(err)
+	require.Equal([]int64{0, 7, 8}, positions(result))
+
+	_, err = b.ApplyMappingSlice([]int64{0, 1, 2})
+	require.EqualError(err, "bitmap: no mapping for position 3")
+}
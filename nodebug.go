@@ -0,0 +1,7 @@
+//go:build !ewah_debug
+// +build !ewah_debug
+
+package ewah
+
+// assertInvariants does nothing unless the ewah_debug build tag is set.
+func (b *Bitmap) assertInvariants() {}
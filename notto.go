@@ -0,0 +1,91 @@
+package ewah
+
+import (
+	"encoding/binary"
+	"io"
+)
+
+// NotTo writes the complement of the bitmap, within its number of bits, to
+// w in the format of Write, without materializing it: words are encoded
+// and written as the ones of the bitmap are read, keeping only a few of them
+// in memory. As the header needs the number of words, the complement is
+// encoded twice, first to count them and then to write them. On error, it
+// returns the number of bytes written before it.
+func (b *Bitmap) NotTo(w io.Writer, order binary.ByteOrder) (int64, error) {
+	words, _ := b.encodeNot(func(...uint64) {})
+
+	cw := &countingWriter{w: w}
+	if err := writeUint32(cw, order, b.Bits()); err != nil {
+		return cw.n, err
+	}
+
+	if err := writeUint32(cw, order, uint32(words)); err != nil {
+		return cw.n, err
+	}
+
+	var buf []byte
+	var err error
+	_, lastrlw := b.encodeNot(func(words ...uint64) {
+		if err != nil {
+			return
+		}
+
+		buf = buf[:0]
+		var tmp [8]byte
+		for _, word := range words {
+			order.PutUint64(tmp[:], word)
+			buf = append(buf, tmp[:]...)
+		}
+		_, err = cw.Write(buf)
+	})
+	if err != nil {
+		return cw.n, err
+	}
+
+	if err := writeUint32(cw, order, uint32(lastrlw)); err != nil {
+		return cw.n, err
+	}
+
+	return cw.n, nil
+}
+
+// encodeNot encodes the complement of the bitmap within its number of bits
+// and passes its words to write, in order, as soon as they can't change
+// anymore. It returns the number of words and the index of the last marker,
+// which is -1 if there are no words.
+func (b *Bitmap) encodeNot(write func(words ...uint64)) (int64, int) {
+	var written int64
+	emit := func(words ...uint64) {
+		write(words...)
+		written += int64(len(words))
+	}
+
+	pending := New()
+	total := wordsFor(b.n)
+	it := b.runs()
+	var words int64
+	for !it.done() && words < total {
+		if it.run > 0 {
+			k := minInt64(it.run, total-words)
+			pending.appendClean(!it.bit, k)
+			it.discard(k)
+			words += k
+		} else {
+			pending.appendLiteral(^it.literal(0))
+			it.discard(1)
+			words++
+		}
+		flushPending(pending, emit)
+	}
+
+	pending.appendClean(true, total-words)
+	pending.n = b.n
+	pending.trimTail()
+
+	lastrlw := int(written) + pending.lastrlw
+	if pending.lastrlw < 0 {
+		lastrlw = int(written) - 1
+	}
+	emit(pending.w...)
+	return written, lastrlw
+}
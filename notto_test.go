@@ -0,0 +1,64 @@
+package ewah
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotTo(t *testing.T) {
+	require := require.New(t)
+	r := rand.New(rand.NewSource(1))
+
+	check := func(b *Bitmap) {
+		t.Helper()
+		var buf bytes.Buffer
+		n, err := b.NotTo(&buf, binary.BigEndian)
+		require.NoError(err)
+		require.Equal(int64(buf.Len()), n)
+
+		c, err := FromReader(&buf, binary.BigEndian)
+		require.NoError(err)
+		requireSameBits(t, not(b, b.n), c)
+		require.NoError(c.checkInvariants())
+	}
+
+	for i := 0; i < 30; i++ {
+		b := New()
+		if i%2 == 0 {
+			b = NewWithBase(int64(r.Intn(3000)))
+		}
+		for _, p := range randomPositions(r, 200000, r.Float64()) {
+			if p >= b.Base() {
+				require.NoError(b.Set(p))
+			}
+		}
+		check(b)
+	}
+
+	// long runs of literals are split in several markers
+	b := New()
+	for p := int64(0); p < 64*3000; p += 3 {
+		require.NoError(b.Set(p))
+	}
+	check(b)
+
+	check(New())
+	check(NewWithBase(640))
+	check(fromPositions(63))
+	check(fromPositions(64 * 10))
+
+	// partial writes return the bytes written before the error
+	var buf bytes.Buffer
+	_, err := b.NotTo(&buf, binary.BigEndian)
+	require.NoError(err)
+	for _, size := range []int{0, 4, 8, 20, buf.Len() - 1} {
+		w := &shortWriter{left: size}
+		n, err := b.NotTo(w, binary.BigEndian)
+		require.Error(err, "size %d", size)
+		require.Equal(int64(w.written), n, "size %d", size)
+	}
+}
@@ -0,0 +1,407 @@
+package ewah
+
+import "sort"
+
+// Operand is a bitmap, or a view over one, that can be used as an operand of
+// the logical operations. It is implemented by *Bitmap and *Complement.
+type Operand interface {
+	// bitLen returns the number of bits of the operand.
+	bitLen() int64
+	// start returns the position of the first encoded word, which is a
+	// multiple of 64. All the bits before it are unset.
+	start() int64
+	// runsFrom returns an iterator over the words of the operand starting at
+	// pos, which must be a multiple of 64 not greater than start.
+	runsFrom(pos int64) runIterator
+}
+
+func (b *Bitmap) bitLen() int64 { return b.n }
+func (b *Bitmap) start() int64  { return b.base }
+
+// merge walks the words of a and b in lockstep, combining them with op, and
+// returns the result as a new bitmap with as many bits as the largest of
+// them. A bitmap with fewer words than the other is treated as if it was
+// padded with zeroes.
+func merge(a, b Operand, op func(x, y uint64) uint64) *Bitmap {
+	return mergeInto(New(), a, b, op, nil)
+}
+
+// mergeInto is like merge, but writes the result to out, reusing its words.
+// out must be neither a nor b. If stats is not nil, it is filled with the
+// statistics of the result.
+func mergeInto(out *Bitmap, a, b Operand, op func(x, y uint64) uint64, stats *OpStats) *Bitmap {
+	n := maxInt64(a.bitLen(), b.bitLen())
+	base := minInt64(a.start(), b.start())
+	out.clear()
+	out.base = base
+	out.hasCard = true
+
+	ia, ib := a.runsFrom(base), b.runsFrom(base)
+	words := base / 64
+	var absorbed int64
+	for !ia.done() || !ib.done() {
+		var k int64
+		switch {
+		case ia.lits == 0 || ia.run > 0:
+			if ib.lits == 0 || ib.run > 0 {
+				k = minClean(&ia, &ib)
+				out.appendWords(op(fill(ia.bit && !ia.done()), fill(ib.bit && !ib.done())), k)
+			} else {
+				k = mergeCleanLiterals(out, &ia, &ib, op, &absorbed)
+			}
+		case ib.lits == 0 || ib.run > 0:
+			k = mergeCleanLiterals(out, &ib, &ia, func(x, y uint64) uint64 { return op(y, x) }, &absorbed)
+		default:
+			k = minInt64(int64(ia.lits), int64(ib.lits))
+			for i := 0; i < int(k); i++ {
+				v := op(ia.literal(i), ib.literal(i))
+				if v == 0 || v == allones {
+					absorbed++
+				}
+				out.appendLiteral(v)
+			}
+		}
+
+		ia.discard(k)
+		ib.discard(k)
+		words += k
+	}
+
+	out.appendClean(false, wordsFor(n)-words)
+	out.n = n
+	out.assertInvariants()
+
+	if stats != nil {
+		*stats = out.opStats()
+		stats.Absorbed = absorbed
+	}
+	return out
+}
+
+// mergeWords walks the words of a and b in lockstep like mergeInto does, but
+// instead of writing the words of the result, it calls fn with every one of
+// them and the number of times it is repeated, until fn returns false.
+func mergeWords(a, b Operand, op func(x, y uint64) uint64, fn func(word uint64, k int64) bool) {
+	base := minInt64(a.start(), b.start())
+	ia, ib := a.runsFrom(base), b.runsFrom(base)
+	for !ia.done() || !ib.done() {
+		var k int64
+		switch {
+		case (ia.lits == 0 || ia.run > 0) && (ib.lits == 0 || ib.run > 0):
+			k = minClean(&ia, &ib)
+			if !fn(op(fill(ia.bit && !ia.done()), fill(ib.bit && !ib.done())), k) {
+				return
+			}
+		case ia.lits == 0 || ia.run > 0:
+			k = int64(ib.lits)
+			if !ia.done() {
+				k = minInt64(ia.run, k)
+			}
+			x := fill(ia.bit && !ia.done())
+			for i := 0; i < int(k); i++ {
+				if !fn(op(x, ib.literal(i)), 1) {
+					return
+				}
+			}
+		case ib.lits == 0 || ib.run > 0:
+			k = int64(ia.lits)
+			if !ib.done() {
+				k = minInt64(ib.run, k)
+			}
+			y := fill(ib.bit && !ib.done())
+			for i := 0; i < int(k); i++ {
+				if !fn(op(ia.literal(i), y), 1) {
+					return
+				}
+			}
+		default:
+			k = minInt64(int64(ia.lits), int64(ib.lits))
+			for i := 0; i < int(k); i++ {
+				if !fn(op(ia.literal(i), ib.literal(i)), 1) {
+					return
+				}
+			}
+		}
+
+		ia.discard(k)
+		ib.discard(k)
+	}
+}
+
+// mergeAny reports whether any bit is set in the result of combining a and b
+// with op, stopping as soon as one is found.
+func mergeAny(a, b Operand, op func(x, y uint64) uint64) bool {
+	var found bool
+	mergeWords(a, b, op, func(word uint64, k int64) bool {
+		found = word != 0
+		return !found
+	})
+	return found
+}
+
+// minClean returns the number of clean words both iterators have in common.
+// An iterator with no words left counts as an endless run of zeroes.
+func minClean(a, b *runIterator) int64 {
+	switch {
+	case a.done():
+		return b.run
+	case b.done():
+		return a.run
+	default:
+		return minInt64(a.run, b.run)
+	}
+}
+
+// mergeTo is like mergeInto, but dst may be a or b, or the bitmap of a
+// complement used as one of them. In that case the result is written to the
+// spare words of dst, which then keeps its previous words as spare ones to
+// be reused by the next operation, so no memory is allocated once both are
+// large enough.
+func mergeTo(dst *Bitmap, a, b Operand, op func(x, y uint64) uint64, stats *OpStats) *Bitmap {
+	if !dst.isOperand(a) && !dst.isOperand(b) {
+		return mergeInto(dst, a, b, op, stats)
+	}
+
+	scratch := Bitmap{w: dst.spare[:0]}
+	mergeInto(&scratch, a, b, op, stats)
+
+	w := dst.w
+	dst.replace(&scratch)
+	dst.spare = w[:0]
+	return dst
+}
+
+// isOperand reports whether the words of o are the ones of the bitmap.
+func (b *Bitmap) isOperand(o Operand) bool {
+	switch o := o.(type) {
+	case *Bitmap:
+		return o == b
+	case *Complement:
+		return o.b == b
+	default:
+		return false
+	}
+}
+
+// mergeCleanLiterals combines the clean run of c with the literals of l,
+// appending the result to out, and returns the number of words consumed.
+// absorbed is increased with the number of literals that result in clean
+// words.
+func mergeCleanLiterals(out *Bitmap, c, l *runIterator, op func(x, y uint64) uint64, absorbed *int64) int64 {
+	k := int64(l.lits)
+	if !c.done() {
+		k = minInt64(c.run, k)
+	}
+
+	x := fill(c.bit && !c.done())
+	// the result does not depend on the literals, so they can be skipped
+	if v := op(x, 0); v == op(x, allones) && (v == 0 || v == allones) {
+		out.appendClean(v == allones, k)
+		*absorbed += k
+		return k
+	}
+
+	for i := 0; i < int(k); i++ {
+		v := op(x, l.literal(i))
+		if v == 0 || v == allones {
+			*absorbed++
+		}
+		out.appendLiteral(v)
+	}
+	return k
+}
+
+func opAnd(x, y uint64) uint64    { return x & y }
+func opOr(x, y uint64) uint64     { return x | y }
+func opXor(x, y uint64) uint64    { return x ^ y }
+func opAndNot(x, y uint64) uint64 { return x &^ y }
+
+func and(a, b *Bitmap) *Bitmap {
+	return merge(a, b, opAnd)
+}
+
+func or(a, b *Bitmap) *Bitmap {
+	return merge(a, b, opOr)
+}
+
+func xor(a, b *Bitmap) *Bitmap {
+	return merge(a, b, opXor)
+}
+
+func andNot(a, b *Bitmap) *Bitmap {
+	return merge(a, b, opAndNot)
+}
+
+// And returns a new bitmap with the intersection of the bitmap and other,
+// computed walking the words of both without decompressing them. The result
+// has as many bits as the largest of them.
+func (b *Bitmap) And(other *Bitmap) *Bitmap {
+	return and(b, other)
+}
+
+// Xor returns a new bitmap with the symmetric difference of the bitmap and
+// other, that is, the positions set in only one of them, computed walking
+// the words of both without decompressing them. The result has as many bits
+// as the largest of them.
+func (b *Bitmap) Xor(other *Bitmap) *Bitmap {
+	return xor(b, other)
+}
+
+// AndNot returns a new bitmap with the positions of the bitmap that are not
+// set in other, computed walking the words of both without decompressing
+// them or materializing the complement of other. The result has as many
+// bits as the largest of them.
+func (b *Bitmap) AndNot(other *Bitmap) *Bitmap {
+	return andNot(b, other)
+}
+
+// AndTo writes the intersection of a and b to dst, replacing its contents
+// and reusing the memory of its words, and returns dst. dst may be a or b.
+func AndTo(dst *Bitmap, a, b Operand) *Bitmap {
+	return mergeTo(dst, a, b, opAnd, nil)
+}
+
+// OrTo writes the union of a and b to dst, replacing its contents and
+// reusing the memory of its words, and returns dst. dst may be a or b.
+func OrTo(dst *Bitmap, a, b Operand) *Bitmap {
+	return mergeTo(dst, a, b, opOr, nil)
+}
+
+// XorTo writes the symmetric difference of a and b to dst, replacing its
+// contents and reusing the memory of its words, and returns dst. dst may be
+// a or b.
+func XorTo(dst *Bitmap, a, b Operand) *Bitmap {
+	return mergeTo(dst, a, b, opXor, nil)
+}
+
+// AndNotTo writes the bits of a that are not set in b to dst, replacing its
+// contents and reusing the memory of its words, and returns dst. dst may be
+// a or b.
+func AndNotTo(dst *Bitmap, a, b Operand) *Bitmap {
+	return mergeTo(dst, a, b, opAndNot, nil)
+}
+
+// Not returns a new bitmap with all the bits of the bitmap, up to Bits(),
+// flipped. Runs of zeroes become runs of ones and the other way around, and
+// literal words are inverted, so the result stays compressed.
+func (b *Bitmap) Not() *Bitmap {
+	return not(b, b.n)
+}
+
+// not returns the complement of b within a universe of n bits.
+func not(b *Bitmap, n int64) *Bitmap {
+	out := New()
+	total := wordsFor(n)
+
+	it := b.runs()
+	var words int64
+	for !it.done() && words < total {
+		if it.run > 0 {
+			k := minInt64(it.run, total-words)
+			out.appendClean(!it.bit, k)
+			it.discard(k)
+			words += k
+			continue
+		}
+
+		k := minInt64(int64(it.lits), total-words)
+		for i := 0; i < int(k); i++ {
+			out.appendLiteral(^it.literal(i))
+		}
+		it.discard(k)
+		words += k
+	}
+
+	out.appendClean(true, total-words)
+	out.n = n
+	out.trimTail()
+	out.assertInvariants()
+	return out
+}
+
+// orMany returns the union of all the given bitmaps, merging them in pairs so
+// no intermediate result grows much larger than needed.
+func orMany(bitmaps ...*Bitmap) *Bitmap {
+	if len(bitmaps) == 0 {
+		return New()
+	}
+
+	for len(bitmaps) > 1 {
+		var next []*Bitmap
+		for i := 0; i+1 < len(bitmaps); i += 2 {
+			next = append(next, or(bitmaps[i], bitmaps[i+1]))
+		}
+
+		if len(bitmaps)%2 != 0 {
+			next = append(next, bitmaps[len(bitmaps)-1])
+		}
+		bitmaps = next
+	}
+
+	return bitmaps[0]
+}
+
+// andMany returns the intersection of all the given bitmaps, starting from
+// the smallest ones and stopping as soon as the result is empty.
+func andMany(bitmaps ...*Bitmap) *Bitmap {
+	if len(bitmaps) == 0 {
+		return New()
+	}
+
+	sorted := make([]*Bitmap, len(bitmaps))
+	copy(sorted, bitmaps)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return len(sorted[i].w) < len(sorted[j].w)
+	})
+
+	var n int64
+	for _, b := range sorted {
+		n = maxInt64(n, b.n)
+	}
+
+	result := sorted[0]
+	if len(sorted) == 1 {
+		return or(result, New())
+	}
+
+	for _, b := range sorted[1:] {
+		if result.empty() {
+			return zeroes(n)
+		}
+		result = and(result, b)
+	}
+	return result
+}
+
+// zeroes returns a bitmap of n bits with no bit set.
+func zeroes(n int64) *Bitmap {
+	b := New()
+	b.appendClean(false, wordsFor(n))
+	b.n = n
+	return b
+}
+
+// IsEmpty reports whether no bit is set in the bitmap. It stops at the first
+// run of ones or literal word with a bit set, so bitmaps with many bits are
+// rarely walked to the end.
+func (b *Bitmap) IsEmpty() bool {
+	return b.empty()
+}
+
+// empty reports whether no bit is set in the bitmap.
+func (b *Bitmap) empty() bool {
+	it := b.runs()
+	for !it.done() {
+		if it.run > 0 && it.bit {
+			return false
+		}
+
+		for i := 0; i < it.lits; i++ {
+			if it.literal(i) != 0 {
+				return false
+			}
+		}
+		it.discard(it.run + int64(it.lits))
+	}
+	return true
+}
@@ -0,0 +1,318 @@
+package ewah
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogicalOps(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+
+	testCases := []struct {
+		name string
+		op   func(a, b *Bitmap) *Bitmap
+		bit  func(x, y bool) bool
+	}{
+		{"and", and, func(x, y bool) bool { return x && y }},
+		{"or", or, func(x, y bool) bool { return x || y }},
+		{"xor", xor, func(x, y bool) bool { return x != y }},
+		{"andNot", andNot, func(x, y bool) bool { return x && !y }},
+		{"And", (*Bitmap).And, func(x, y bool) bool { return x && y }},
+		{"Xor", (*Bitmap).Xor, func(x, y bool) bool { return x != y }},
+		{"AndNot", (*Bitmap).AndNot, func(x, y bool) bool { return x && !y }},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			require := require.New(t)
+			for i := 0; i < 50; i++ {
+				pa := randomPositions(r, int64(r.Intn(5000)), r.Float64())
+				pb := randomPositions(r, int64(r.Intn(5000)), r.Float64())
+				a, b := fromPositions(pa...), fromPositions(pb...)
+
+				result := tt.op(a, b)
+				require.Equal(expectedOp(pa, pb, tt.bit), positions(result))
+				require.Equal(maxInt64(a.n, b.n), result.n)
+				require.Equal(wordsFor(result.n), countWords(result))
+			}
+		})
+	}
+}
+
+func TestNot(t *testing.T) {
+	require := require.New(t)
+	r := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 50; i++ {
+		pa := randomPositions(r, int64(r.Intn(5000)), r.Float64())
+		a := fromPositions(pa...)
+		n := a.n + int64(r.Intn(200))
+
+		result := not(a, n)
+		var expected []int64
+		for i, j := int64(0), 0; i < n; i++ {
+			if j < len(pa) && pa[j] == i {
+				j++
+			} else {
+				expected = append(expected, i)
+			}
+		}
+
+		require.Equal(expected, positions(result))
+		require.Equal(wordsFor(n), countWords(result))
+		require.Equal(pa, positions(not(result, n)))
+
+		flipped := a.Not()
+		require.Equal(a.n, flipped.n)
+		for _, p := range positions(flipped) {
+			require.True(p < a.n && !a.Get(p))
+		}
+		require.Equal(a.n-int64(len(pa)), int64(len(positions(flipped))))
+		require.Equal(pa, positions(flipped.Not()))
+	}
+}
+
+func TestNotAllOnes(t *testing.T) {
+	require := require.New(t)
+
+	result := not(New(), 130)
+	require.Equal([]uint64{
+		uint64(newRlw(true, 2, 1)),
+		uint64(3) << 62,
+	}, result.w)
+	require.Equal(int64(130), result.n)
+}
+
+func TestSetAfterOp(t *testing.T) {
+	require := require.New(t)
+
+	a := fromPositions(1, 2, 130)
+	b := fromPositions(1, 130)
+
+	result := andNot(a, b)
+	require.Equal([]int64{2}, positions(result))
+
+	require.NoError(result.Set(140))
+	require.NoError(result.Set(300))
+	require.Equal([]int64{2, 140, 300}, positions(result))
+}
+
+func TestManyOps(t *testing.T) {
+	require := require.New(t)
+	r := rand.New(rand.NewSource(1))
+
+	var bitmaps []*Bitmap
+	var ps [][]int64
+	for i := 0; i < 7; i++ {
+		p := randomPositions(r, int64(r.Intn(5000)), 0.9)
+		ps = append(ps, p)
+		bitmaps = append(bitmaps, fromPositions(p...))
+	}
+
+	expectedOr, expectedAnd := ps[0], ps[0]
+	for _, p := range ps[1:] {
+		expectedOr = expectedOp(expectedOr, p, func(x, y bool) bool { return x || y })
+		expectedAnd = expectedOp(expectedAnd, p, func(x, y bool) bool { return x && y })
+	}
+
+	require.Equal(expectedOr, positions(orMany(bitmaps...)))
+	require.Equal(expectedAnd, positions(andMany(bitmaps...)))
+
+	single := andMany(bitmaps[0])
+	require.Equal(ps[0], positions(single))
+	require.NotSame(bitmaps[0], single)
+
+	empty := andMany(fromPositions(1), fromPositions(2), fromPositions(1000))
+	require.True(empty.empty())
+	require.Equal(int64(1001), empty.n)
+}
+
+func TestIsEmpty(t *testing.T) {
+	require := require.New(t)
+
+	require.True(New().IsEmpty())
+	require.True(zeroes(10000).IsEmpty())
+	require.True(NewWithBase(6400).IsEmpty())
+	require.True(andNot(fromPositions(1, 2000), fromPositions(1, 2000)).IsEmpty())
+	require.False(fromPositions(5000).IsEmpty())
+	require.False(zeroes(1000).Not().IsEmpty())
+}
+
+func TestFastOr(t *testing.T) {
+	require := require.New(t)
+	r := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 30; i++ {
+		var bitmaps []*Bitmap
+		for j := r.Intn(20); j >= 0; j-- {
+			b := New()
+			if j%3 == 0 {
+				b = NewWithBase(int64(r.Intn(20000)))
+			}
+			for _, p := range randomPositions(r, int64(r.Intn(20000)), r.Float64()*r.Float64()) {
+				if p >= b.Base() {
+					require.NoError(b.Set(p))
+				}
+			}
+			bitmaps = append(bitmaps, b)
+		}
+
+		expected := orMany(bitmaps...)
+		result := FastOr(bitmaps...)
+		require.Equal(expected.n, result.n)
+		require.Equal(expected.w, result.w)
+	}
+
+	require.Equal(int64(0), FastOr().n)
+	require.Equal([]int64{1, 2}, positions(FastOr(fromPositions(1, 2))))
+}
+
+func TestFastAnd(t *testing.T) {
+	require := require.New(t)
+	r := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 30; i++ {
+		var bitmaps []*Bitmap
+		for j := r.Intn(6); j >= 0; j-- {
+			b := New()
+			if j%3 == 0 {
+				b = NewWithBase(int64(r.Intn(2000)))
+			}
+			for _, p := range randomPositions(r, int64(r.Intn(20000)), 0.5+r.Float64()/2) {
+				if p >= b.Base() {
+					require.NoError(b.Set(p))
+				}
+			}
+			bitmaps = append(bitmaps, b)
+		}
+
+		expected := andMany(bitmaps...)
+		result := FastAnd(bitmaps...)
+		require.Equal(expected.n, result.n)
+		require.Equal(expected.w, result.w)
+	}
+
+	// operands with no set bits left stop the pass
+	big := fromPositions(randomPositions(r, 100000, 0.5)...)
+	result := FastAnd(big, New(), big)
+	require.Equal(big.n, result.n)
+	require.True(result.IsEmpty())
+	require.NoError(result.Validate())
+
+	result = FastAnd(big, fromPositions(big.Minimum()), big)
+	require.Equal([]int64{big.Minimum()}, positions(result))
+	require.Equal(big.n, result.n)
+	require.Equal(andMany(big, fromPositions(big.Minimum()), big).w, result.w)
+
+	require.Equal(int64(0), FastAnd().n)
+	require.Equal([]int64{2}, positions(FastAnd(fromPositions(1, 2), fromPositions(2, 3))))
+}
+
+func BenchmarkFastAndEmpty(b *testing.B) {
+	var bitmaps []*Bitmap
+	for i := 0; i < 10; i++ {
+		bitmaps = append(bitmaps, Build(1000000, func(pos int64) bool { return pos/64%3 != 0 }))
+	}
+
+	// the intersection has no set bits after the first word
+	small := fromPositions(5)
+	small.extend(1000000)
+	bitmaps = append(bitmaps, small)
+
+	for i := 0; i < b.N; i++ {
+		FastAnd(bitmaps...)
+	}
+}
+
+// expectedOp computes op over two sorted lists of positions.
+func expectedOp(a, b []int64, op func(x, y bool) bool) []int64 {
+	set := make(map[int64]bool)
+	var max int64 = -1
+	for _, p := range a {
+		set[p] = true
+		max = maxInt64(max, p)
+	}
+
+	inB := make(map[int64]bool)
+	for _, p := range b {
+		inB[p] = true
+		max = maxInt64(max, p)
+	}
+
+	var result []int64
+	for i := int64(0); i <= max; i++ {
+		if op(set[i], inB[i]) {
+			result = append(result, i)
+		}
+	}
+	return result
+}
+
+// countWords returns the number of words encoded in the bitmap.
+func countWords(b *Bitmap) int64 {
+	var n int64
+	it := newRunIterator(b.w)
+	for !it.done() {
+		n += it.run + int64(it.lits)
+		it.discard(it.run + int64(it.lits))
+	}
+	return n
+}
+
+func TestDestinationOps(t *testing.T) {
+	require := require.New(t)
+	r := rand.New(rand.NewSource(1))
+
+	a := fromPositions(randomPositions(r, 5000, 0.5)...)
+	b := fromPositions(randomPositions(r, 5000, 0.5)...)
+	dst := fromPositions(randomPositions(r, 20000, 0.5)...)
+
+	testCases := []struct {
+		name string
+		fn   func(dst *Bitmap, a, b Operand) *Bitmap
+		op   func(a, b *Bitmap) *Bitmap
+	}{
+		{"AndTo", AndTo, and},
+		{"OrTo", OrTo, or},
+		{"XorTo", XorTo, xor},
+		{"AndNotTo", AndNotTo, andNot},
+	}
+
+	for _, tt := range testCases {
+		words := &dst.w[:1][0]
+		require.Same(dst, tt.fn(dst, a, b), tt.name)
+		require.Equal(tt.op(a, b), dst, tt.name)
+		require.Same(words, &dst.w[0], tt.name)
+
+		allocs := testing.AllocsPerRun(10, func() {
+			tt.fn(dst, a, b)
+		})
+		require.Zero(allocs, tt.name)
+	}
+}
+
+func TestDestinationOpsAliased(t *testing.T) {
+	require := require.New(t)
+	r := rand.New(rand.NewSource(1))
+
+	ops := []func(dst *Bitmap, a, b Operand) *Bitmap{AndTo, OrTo, XorTo, AndNotTo}
+	for _, fn := range ops {
+		a := fromPositions(randomPositions(r, 5000, 0.5)...)
+		b := fromPositions(randomPositions(r, 8000, 0.5)...)
+
+		expected := fn(New(), a, b)
+		require.Same(a, fn(a, a, b))
+		require.Equal(expected.w, a.w)
+
+		expected = fn(New(), a, b)
+		require.Same(b, fn(b, a, b))
+		require.Equal(expected.w, b.w)
+
+		expected = fn(New(), a, a.Complement(10000))
+		fn(a, a, a.Complement(10000))
+		require.Equal(expected.w, a.w)
+		require.NoError(a.checkInvariants())
+	}
+}
@@ -0,0 +1,88 @@
+package ewah
+
+import "fmt"
+
+// Op is a logical operation between two bitmaps.
+type Op byte
+
+const (
+	// AndOp is the intersection of both bitmaps.
+	AndOp Op = iota
+	// OrOp is the union of both bitmaps.
+	OrOp
+	// XorOp is the symmetric difference of both bitmaps.
+	XorOp
+	// AndNotOp is the bits of the first bitmap that are not set in the
+	// second one.
+	AndNotOp
+)
+
+func (op Op) String() string {
+	switch op {
+	case AndOp:
+		return "AND"
+	case OrOp:
+		return "OR"
+	case XorOp:
+		return "XOR"
+	case AndNotOp:
+		return "AND NOT"
+	default:
+		return fmt.Sprintf("Op(%d)", byte(op))
+	}
+}
+
+// words returns the function that combines the words of the operands.
+func (op Op) words() func(x, y uint64) uint64 {
+	switch op {
+	case AndOp:
+		return opAnd
+	case OrOp:
+		return opOr
+	case XorOp:
+		return opXor
+	case AndNotOp:
+		return opAndNot
+	default:
+		panic(fmt.Sprintf("bitmap: unknown operation %s", op))
+	}
+}
+
+// OpStats describes how the result of a logical operation is encoded. A
+// result with many literals and few clean words compresses poorly, which may
+// be a sign to switch to a different strategy.
+type OpStats struct {
+	// Runs is the number of runs of clean words.
+	Runs int64
+	// CleanWords is the number of clean words in all runs.
+	CleanWords int64
+	// Literals is the number of literal words.
+	Literals int64
+	// Absorbed is the number of words that were literals in any of the
+	// operands but are clean words in the result.
+	Absorbed int64
+}
+
+// MergeTo writes the result of the given operation between a and b to dst,
+// like AndTo and the rest of functions of the kind do, and returns dst. If
+// stats is not nil, it is filled with the statistics of the result.
+func MergeTo(dst *Bitmap, a, b Operand, op Op, stats *OpStats) *Bitmap {
+	return mergeTo(dst, a, b, op.words(), stats)
+}
+
+// opStats returns the statistics of the words of the bitmap, except for the
+// number of words absorbed, which only makes sense for the result of an
+// operation.
+func (b *Bitmap) opStats() OpStats {
+	var stats OpStats
+	for i := 0; i < len(b.w); {
+		r := rlw(b.w[i])
+		if r.k() > 0 {
+			stats.Runs++
+			stats.CleanWords += int64(r.k())
+		}
+		stats.Literals += int64(r.l())
+		i += int(r.l()) + 1
+	}
+	return stats
+}
@@ -0,0 +1,55 @@
+package ewah
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergeTo(t *testing.T) {
+	require := require.New(t)
+	r := rand.New(rand.NewSource(1))
+
+	a := fromPositions(randomPositions(r, 5000, 0.5)...)
+	b := fromPositions(randomPositions(r, 5000, 0.5)...)
+
+	ops := []struct {
+		op Op
+		fn func(a, b *Bitmap) *Bitmap
+	}{
+		{AndOp, and},
+		{OrOp, or},
+		{XorOp, xor},
+		{AndNotOp, andNot},
+	}
+
+	for _, tt := range ops {
+		var stats OpStats
+		result := MergeTo(New(), a, b, tt.op, &stats)
+		require.Equal(tt.fn(a, b), result, tt.op.String())
+		require.Equal(countWords(result), stats.CleanWords+stats.Literals, tt.op.String())
+		require.True(stats.Runs <= int64(len(result.w))-stats.Literals, tt.op.String())
+		require.Equal(result, MergeTo(New(), a, b, tt.op, nil))
+	}
+
+	require.Equal("AND NOT", AndNotOp.String())
+	require.Equal("Op(9)", Op(9).String())
+	require.Panics(func() { MergeTo(New(), a, b, Op(9), nil) })
+}
+
+func TestOpStatsAbsorbed(t *testing.T) {
+	require := require.New(t)
+
+	// both literals cancel out
+	a := fromPositions(1, 3, 130)
+	b := fromPositions(1, 3, 131)
+
+	var stats OpStats
+	MergeTo(New(), a, b, XorOp, &stats)
+	require.Equal(OpStats{Runs: 1, CleanWords: 2, Literals: 1, Absorbed: 1}, stats)
+
+	// the literals of a fall in a run of zeroes of b
+	MergeTo(New(), a, zeroes(192), AndOp, &stats)
+	require.Equal(OpStats{Runs: 1, CleanWords: 3, Literals: 0, Absorbed: 2}, stats)
+}
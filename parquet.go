@@ -0,0 +1,120 @@
+package ewah
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/bits"
+)
+
+// MarshalParquetRLE encodes the first n bits of the bitmap using the Parquet
+// RLE/bit-packing hybrid encoding with a bit width of 1, as used for boolean
+// values and definition levels. Clean runs are written as RLE runs and
+// literal words as bit-packed runs, so no dense copy of the bitmap is made.
+// The result does not include the 4-byte length prefix some pages require.
+func (b *Bitmap) MarshalParquetRLE(n int64) []byte {
+	var buf []byte
+	var pos int64
+	var packed []byte
+
+	flush := func() {
+		if len(packed) > 0 {
+			buf = appendUvarint(buf, uint64(len(packed))<<1|1)
+			buf = append(buf, packed...)
+			packed = packed[:0]
+		}
+	}
+
+	rle := func(bit bool, count int64) {
+		flush()
+		buf = appendUvarint(buf, uint64(count)<<1)
+		if bit {
+			buf = append(buf, 1)
+		} else {
+			buf = append(buf, 0)
+		}
+	}
+
+	it := b.runs()
+	for pos < n && !it.done() {
+		if it.run > 0 {
+			count := minInt64(it.run*64, n-pos)
+			rle(it.bit, count)
+			pos += count
+			it.discard(it.run)
+			continue
+		}
+
+		word := it.literal(0)
+		count := minInt64(64, n-pos)
+		if count < 64 {
+			word &= ^(allones >> uint(count))
+		}
+
+		var group [8]byte
+		binary.LittleEndian.PutUint64(group[:], bits.Reverse64(word))
+		packed = append(packed, group[:(count+7)/8]...)
+		pos += count
+		it.discard(1)
+	}
+
+	if pos < n {
+		rle(false, n-pos)
+	}
+	flush()
+
+	return buf
+}
+
+func appendUvarint(buf []byte, v uint64) []byte {
+	var tmp [binary.MaxVarintLen64]byte
+	return append(buf, tmp[:binary.PutUvarint(tmp[:], v)]...)
+}
+
+// FromParquetRLE creates a bitmap of n bits from the given values encoded
+// with the Parquet RLE/bit-packing hybrid encoding with a bit width of 1.
+// Data must not include the 4-byte length prefix some pages have.
+func FromParquetRLE(data []byte, n int64) (*Bitmap, error) {
+	s := newStreamBuilder()
+	for s.n < n {
+		header, size := binary.Uvarint(data)
+		if size <= 0 {
+			return nil, fmt.Errorf("bitmap: invalid parquet run header after %d values", s.n)
+		}
+		data = data[size:]
+
+		if header&1 == 0 {
+			if len(data) < 1 {
+				return nil, fmt.Errorf("bitmap: missing parquet RLE value after %d values", s.n)
+			}
+
+			if data[0] > 1 {
+				return nil, fmt.Errorf("bitmap: invalid parquet RLE value %d for a bit width of 1", data[0])
+			}
+
+			s.addRun(data[0] == 1, minInt64(int64(header>>1), n-s.n))
+			data = data[1:]
+			continue
+		}
+
+		groups := header >> 1
+		if uint64(len(data)) < groups {
+			return nil, fmt.Errorf("bitmap: parquet bit-packed run of %d groups has only %d bytes", groups, len(data))
+		}
+
+		for len(data) > 0 && groups > 0 && s.n < n {
+			var group [8]byte
+			size := copy(group[:minInt64(int64(groups), 8)], data)
+			data, groups = data[size:], groups-uint64(size)
+
+			count := int(minInt64(int64(size)*8, n-s.n))
+			v := bits.Reverse64(binary.LittleEndian.Uint64(group[:]))
+			if count < 64 {
+				v &= ^(allones >> uint(count))
+			}
+			s.addBits(v, count)
+		}
+		data = data[groups:]
+	}
+
+	return s.bitmap(), nil
+}
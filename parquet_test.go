@@ -0,0 +1,98 @@
+package ewah
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMarshalParquetRLE(t *testing.T) {
+	require := require.New(t)
+
+	var ps []int64
+	for i := int64(0); i < 128; i++ {
+		ps = append(ps, i)
+	}
+	ps = append(ps, 128, 130)
+
+	b := fromPositions(ps...)
+	require.Equal([]byte{
+		// 128 ones
+		0x80, 0x02, 0x01,
+		// 3 groups of 8 bit-packed values
+		0x07, 0x05, 0x00, 0x00,
+	}, b.MarshalParquetRLE(146))
+
+	require.Equal([]byte{
+		// 128 ones
+		0x80, 0x02, 0x01,
+		// 8 groups of 8 bit-packed values
+		0x11, 0x05, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		// 8 zeroes past the end of the bitmap
+		0x10, 0x00,
+	}, b.MarshalParquetRLE(200))
+
+	require.Equal([]byte{0x80, 0x02, 0x01, 0x03, 0x01}, b.MarshalParquetRLE(129))
+	require.Empty(b.MarshalParquetRLE(0))
+}
+
+func TestFromParquetRLE(t *testing.T) {
+	require := require.New(t)
+
+	data := []byte{
+		// 3 ones
+		0x06, 0x01,
+		// 1 group of 8 bit-packed values
+		0x03, 0x55,
+		// 70 zeroes
+		0x8c, 0x01, 0x00,
+		// 2 ones
+		0x04, 0x01,
+	}
+
+	b, err := FromParquetRLE(data, 83)
+	require.NoError(err)
+	require.Equal([]int64{0, 1, 2, 3, 5, 7, 9, 81, 82}, positions(b))
+	require.Equal(int64(83), b.n)
+
+	b, err = FromParquetRLE(data, 6)
+	require.NoError(err)
+	require.Equal([]int64{0, 1, 2, 3, 5}, positions(b))
+	require.Equal(int64(6), b.n)
+}
+
+func TestFromParquetRLEErrors(t *testing.T) {
+	testCases := []struct {
+		data []byte
+		err  string
+	}{
+		{nil, "bitmap: invalid parquet run header after 0 values"},
+		{[]byte{0x06}, "bitmap: missing parquet RLE value after 0 values"},
+		{[]byte{0x06, 0x02}, "bitmap: invalid parquet RLE value 2 for a bit width of 1"},
+		{[]byte{0x05, 0x01}, "bitmap: parquet bit-packed run of 2 groups has only 1 bytes"},
+		{[]byte{0x06, 0x01}, "bitmap: invalid parquet run header after 3 values"},
+	}
+
+	for _, tt := range testCases {
+		_, err := FromParquetRLE(tt.data, 10)
+		require.EqualError(t, err, tt.err)
+	}
+}
+
+func TestParquetRLERoundTrip(t *testing.T) {
+	require := require.New(t)
+	r := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 50; i++ {
+		ps := randomPositions(r, int64(r.Intn(10000)), r.Float64())
+		b := fromPositions(ps...)
+		n := b.n + int64(r.Intn(100))
+
+		result, err := FromParquetRLE(b.MarshalParquetRLE(n), n)
+		require.NoError(err)
+		require.Equal(ps, positions(result))
+		require.Equal(n, result.n)
+		require.Equal(wordsFor(n), countWords(result))
+	}
+}
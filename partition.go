@@ -0,0 +1,96 @@
+package ewah
+
+// PartitionMode is the way positions are assigned to the bitmaps returned by
+// Partition.
+type PartitionMode byte
+
+const (
+	// PartitionRange assigns contiguous, word-aligned ranges of positions of
+	// about the same size to each bitmap.
+	PartitionRange PartitionMode = iota
+	// PartitionHash assigns each position to a bitmap according to its hash,
+	// which spreads dense regions evenly among all bitmaps.
+	PartitionHash
+)
+
+// Partition splits the bitmap into n disjoint bitmaps using the given mode.
+// Positions are not renumbered, so the union of all the partitions is the
+// original bitmap. Instead, each partition is based, as with NewWithBase, at
+// the start of its range in range partitions, or at the base of the bitmap
+// if it's greater, so the positions before it take no space. All partitions
+// have as many bits as the bitmap.
+func (b *Bitmap) Partition(n int, mode PartitionMode) []*Bitmap {
+	if n <= 0 {
+		return nil
+	}
+
+	var parts []*Bitmap
+	switch mode {
+	case PartitionHash:
+		parts = make([]*Bitmap, n)
+		for i := range parts {
+			parts[i] = NewWithBase(b.base)
+		}
+
+		b.each(func(pos int64) bool {
+			_ = parts[int(mix64(uint64(pos))%uint64(n))].Set(pos)
+			return true
+		})
+	default:
+		parts = b.partitionRange(n)
+	}
+
+	for _, p := range parts {
+		p.extend(b.n)
+	}
+
+	return parts
+}
+
+// partitionRange copies the words of the bitmap into n parts, giving each of
+// them about the same number of words starting at their base.
+func (b *Bitmap) partitionRange(n int) []*Bitmap {
+	parts := make([]*Bitmap, n)
+	total := wordsFor(b.n)
+	per := (total + int64(n) - 1) / int64(n)
+	it := b.runs()
+	for i := range parts {
+		words := minInt64(int64(i)*per, total)
+		p := NewWithBase(minInt64(maxInt64(words*64, b.base), b.n))
+		words = minInt64(words, p.base/64)
+		for left := per; left > 0 && !it.done(); {
+			k := minInt64(it.run, left)
+			if skip := p.base/64 - words; k > 0 && skip > 0 {
+				// words before the base of the bitmap are all zeroes
+				k = minInt64(k, skip)
+			} else if k > 0 {
+				p.appendClean(it.bit, k)
+			} else {
+				k = minInt64(int64(it.lits), left)
+				for j := 0; j < int(k); j++ {
+					p.appendLiteral(it.literal(j))
+				}
+			}
+
+			it.discard(k)
+			left -= k
+			words += k
+		}
+
+		p.n = maxInt64(minInt64(words*64, b.n), p.base)
+		parts[i] = p
+	}
+
+	return parts
+}
+
+// mix64 is the finalizer of the SplitMix64 generator, which scrambles the
+// bits of x.
+func mix64(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
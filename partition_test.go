@@ -0,0 +1,94 @@
+package ewah
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPartition(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+
+	for _, mode := range []PartitionMode{PartitionRange, PartitionHash} {
+		require := require.New(t)
+		for i := 0; i < 20; i++ {
+			ps := randomPositions(r, int64(r.Intn(20000)), r.Float64())
+			b := fromPositions(ps...)
+
+			n := r.Intn(8) + 1
+			parts := b.Partition(n, mode)
+			require.Len(parts, n)
+
+			var total int
+			per := (wordsFor(b.n) + int64(n) - 1) / int64(n)
+			for i, p := range parts {
+				require.Equal(b.n, p.n)
+				require.Equal(wordsFor(b.n), p.base/64+countWords(p))
+				if mode == PartitionRange {
+					require.Equal(minInt64(int64(i)*per*64, b.n)/64*64, p.Base())
+				} else {
+					require.Equal(b.Base(), p.Base())
+				}
+				total += len(positions(p))
+				for _, q := range parts[i+1:] {
+					require.True(and(p, q).empty())
+				}
+			}
+
+			require.Equal(len(ps), total)
+			require.Equal(ps, positions(orMany(parts...)))
+		}
+	}
+}
+
+func TestPartitionRange(t *testing.T) {
+	require := require.New(t)
+
+	b := fromPositions(0, 64, 128, 192, 256)
+	parts := b.Partition(2, PartitionRange)
+	require.Equal([]int64{0, 64, 128}, positions(parts[0]))
+	require.Equal([]int64{192, 256}, positions(parts[1]))
+	require.Equal(int64(0), parts[0].Base())
+	require.Equal(int64(192), parts[1].Base())
+	require.Equal([]uint64{uint64(newRlw(false, 0, 2)), 1 << 63, 1 << 63}, parts[1].w)
+
+	parts = b.Partition(10, PartitionRange)
+	require.Len(parts, 10)
+	for i, p := range parts {
+		require.Equal(wordsFor(b.n), p.base/64+countWords(p))
+		require.Equal(minInt64(int64(i)*64, 256), p.Base())
+		if i < 5 {
+			require.Equal([]int64{int64(i) * 64}, positions(p))
+		} else {
+			require.True(p.empty())
+		}
+	}
+
+	require.Nil(b.Partition(0, PartitionRange))
+}
+
+func TestPartitionBase(t *testing.T) {
+	require := require.New(t)
+
+	b := NewWithBase(6400)
+	require.NoError(b.Set(6400))
+	require.NoError(b.Set(6500))
+	require.NoError(b.Set(7000))
+
+	parts := b.Partition(2, PartitionRange)
+	require.Equal(int64(6400), parts[0].Base())
+	require.Equal(int64(6400), parts[1].Base())
+	require.True(parts[0].empty())
+	require.Equal([]int64{6400, 6500, 7000}, positions(parts[1]))
+
+	for _, mode := range []PartitionMode{PartitionRange, PartitionHash} {
+		parts := b.Partition(3, mode)
+		for _, p := range parts {
+			require.Equal(b.Base(), p.Base())
+			require.Equal(b.n, p.n)
+			require.Equal(wordsFor(b.n), p.base/64+countWords(p))
+		}
+		require.Equal(positions(b), positions(orMany(parts...)))
+	}
+}
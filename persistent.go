@@ -0,0 +1,184 @@
+package ewah
+
+const (
+	// persistentChunkBits is the number of bits of every chunk of a
+	// Persistent bitmap.
+	persistentChunkBits = 1 << 14
+	// persistentFanout is the number of children of every node of the tree
+	// of chunks of a Persistent bitmap.
+	persistentFanout = 32
+)
+
+// Persistent is an immutable bitmap whose Set and Unset return a new version
+// instead of modifying it. The bits are split in chunks, each one encoded on
+// its own, which are the leaves of a tree. A new version only copies the
+// chunk that changed and the nodes on its path to the root, sharing all the
+// rest with the previous one, so many versions can be kept cheaply, such as
+// in MVCC systems. As versions never change, they can be read from several
+// goroutines at once. Unlike in a Bitmap, positions can be set and unset in
+// any order.
+type Persistent struct {
+	root *persistentNode
+	// depth is the number of levels of nodes above the leaves.
+	depth int
+	n     int64
+}
+
+// persistentNode is a node of the tree of a Persistent bitmap. Leaves have
+// chunks and the rest have children, any of which can be nil if it has no
+// bits set.
+type persistentNode struct {
+	children []*persistentNode
+	chunks   []*Bitmap
+}
+
+// NewPersistent creates an empty persistent bitmap.
+func NewPersistent() *Persistent {
+	return &Persistent{}
+}
+
+// Bits returns the number of uncompressed bits in the bitmap, which is one
+// more than the highest position ever set.
+func (p *Persistent) Bits() int64 {
+	return p.n
+}
+
+// Get returns the bit at the given position, being true 1 and false 0.
+func (p *Persistent) Get(pos int64) bool {
+	if pos < 0 || pos >= p.n {
+		return false
+	}
+
+	chunk := p.chunk(pos / persistentChunkBits)
+	return chunk != nil && chunk.Get(pos%persistentChunkBits)
+}
+
+// Set returns a new version of the bitmap with the bit at the given
+// position set to 1, or the same one if it already was.
+func (p *Persistent) Set(pos int64) *Persistent {
+	if pos < 0 || p.Get(pos) {
+		return p
+	}
+
+	next := p.with(pos, func(chunk *Bitmap, single *Bitmap) *Bitmap {
+		if chunk == nil {
+			return single
+		}
+		return or(chunk, single)
+	})
+	next.n = maxInt64(p.n, pos+1)
+	return next
+}
+
+// Unset returns a new version of the bitmap with the bit at the given
+// position set to 0, or the same one if it already was. The number of bits
+// of the bitmap does not change.
+func (p *Persistent) Unset(pos int64) *Persistent {
+	if !p.Get(pos) {
+		return p
+	}
+
+	return p.with(pos, func(chunk *Bitmap, single *Bitmap) *Bitmap {
+		if result := andNot(chunk, single); !result.empty() {
+			return result
+		}
+		return nil
+	})
+}
+
+// with returns a copy of the bitmap where the chunk with the given position
+// is replaced with the result of calling change with it, or nil if there is
+// no such chunk, and a bitmap with only the position, relative to the chunk,
+// set. Only the nodes on the path to the chunk are copied.
+func (p *Persistent) with(pos int64, change func(chunk, single *Bitmap) *Bitmap) *Persistent {
+	idx := pos / persistentChunkBits
+	next := &Persistent{root: p.root, depth: p.depth, n: p.n}
+	for idx >= next.capacity() {
+		root := &persistentNode{children: make([]*persistentNode, persistentFanout)}
+		root.children[0] = next.root
+		next.root, next.depth = root, next.depth+1
+	}
+
+	single := New()
+	single.Set(pos % persistentChunkBits)
+
+	next.root = next.root.copy(next.depth)
+	node := next.root
+	for level := next.depth; level > 0; level-- {
+		i := idx / pow(persistentFanout, level) % persistentFanout
+		node.children[i] = node.children[i].copy(level - 1)
+		node = node.children[i]
+	}
+
+	i := idx % persistentFanout
+	chunk := change(node.chunks[i], single)
+	if chunk != nil {
+		chunk.Freeze()
+	}
+	node.chunks[i] = chunk
+	return next
+}
+
+// capacity returns the number of chunks the tree can hold with its depth.
+func (p *Persistent) capacity() int64 {
+	return pow(persistentFanout, p.depth+1)
+}
+
+// chunk returns the chunk with the given index, or nil if it has no bits
+// set.
+func (p *Persistent) chunk(idx int64) *Bitmap {
+	if idx >= p.capacity() {
+		return nil
+	}
+
+	node := p.root
+	for level := p.depth; level > 0 && node != nil; level-- {
+		node = node.children[idx/pow(persistentFanout, level)%persistentFanout]
+	}
+
+	if node == nil {
+		return nil
+	}
+	return node.chunks[idx%persistentFanout]
+}
+
+// copy returns a copy of the node, which is at the given level, sharing its
+// children, or a new empty one if it is nil.
+func (n *persistentNode) copy(level int) *persistentNode {
+	c := &persistentNode{}
+	if level > 0 {
+		c.children = make([]*persistentNode, persistentFanout)
+		if n != nil {
+			copy(c.children, n.children)
+		}
+	} else {
+		c.chunks = make([]*Bitmap, persistentFanout)
+		if n != nil {
+			copy(c.chunks, n.chunks)
+		}
+	}
+	return c
+}
+
+// Bitmap returns a new Bitmap with the bits of this version.
+func (p *Persistent) Bitmap() *Bitmap {
+	s := newStreamBuilder()
+	for from := int64(0); from < p.n; from += persistentChunkBits {
+		count := minInt64(persistentChunkBits, p.n-from)
+		if chunk := p.chunk(from / persistentChunkBits); chunk != nil {
+			s.addSlice(chunk, 0, count)
+		} else {
+			s.addRun(false, count)
+		}
+	}
+	return s.bitmap()
+}
+
+// pow returns x to the power of y.
+func pow(x int64, y int) int64 {
+	result := int64(1)
+	for ; y > 0; y-- {
+		result *= x
+	}
+	return result
+}
@@ -0,0 +1,82 @@
+package ewah
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPersistent(t *testing.T) {
+	require := require.New(t)
+	r := rand.New(rand.NewSource(1))
+
+	type version struct {
+		p   *Persistent
+		set map[int64]bool
+	}
+
+	versions := []version{{NewPersistent(), map[int64]bool{}}}
+	for i := 0; i < 2000; i++ {
+		prev := versions[len(versions)-1]
+		set := make(map[int64]bool, len(prev.set))
+		for pos := range prev.set {
+			set[pos] = true
+		}
+
+		pos := int64(r.Intn(200000))
+		if i%50 == 0 {
+			pos = int64(r.Intn(1 << 30))
+		}
+
+		var p *Persistent
+		if r.Intn(3) == 0 {
+			p = prev.p.Unset(pos)
+			delete(set, pos)
+		} else {
+			p = prev.p.Set(pos)
+			set[pos] = true
+		}
+		versions = append(versions, version{p, set})
+	}
+
+	// all versions are still intact
+	for i, v := range versions {
+		if i%100 != 0 && i != len(versions)-1 {
+			continue
+		}
+
+		var expected []int64
+		for pos := range v.set {
+			expected = append(expected, pos)
+		}
+		sort.Slice(expected, func(i, j int) bool { return expected[i] < expected[j] })
+
+		b := v.p.Bitmap()
+		require.Equal(v.p.Bits(), b.n)
+		var actual []int64
+		b.each(func(pos int64) bool {
+			actual = append(actual, pos)
+			require.True(v.p.Get(pos))
+			return true
+		})
+		require.Equal(expected, actual, "version %d", i)
+	}
+
+	p := NewPersistent().Set(5)
+	require.True(p == p.Set(5))
+	require.True(p == p.Unset(6))
+	require.True(p == p.Set(-1))
+	require.False(p.Get(-1))
+	require.False(p.Unset(5).Get(5))
+	require.Equal(int64(6), p.Unset(5).Bits())
+	require.Nil(p.Unset(5).chunk(0))
+
+	// chunks that didn't change are shared
+	p = p.Set(persistentChunkBits * 40)
+	q := p.Set(7)
+	require.True(p.chunk(40) == q.chunk(40))
+	require.True(p.root.children[1] == q.root.children[1])
+	require.False(p.chunk(0) == q.chunk(0))
+}
@@ -0,0 +1,58 @@
+package ewah
+
+import "sync"
+
+// AppendPipeline builds a single bitmap from positions set by several
+// goroutines at once. Each goroutine gets its own Producer, which only needs
+// its positions to be in ascending order, and appends to a bitmap of its own
+// without any locking. Once all producers are done, Bitmap merges them.
+type AppendPipeline struct {
+	mu        sync.Mutex
+	producers []*Producer
+}
+
+// NewAppendPipeline creates a new empty pipeline.
+func NewAppendPipeline() *AppendPipeline {
+	return &AppendPipeline{}
+}
+
+// Producer returns a new producer for the pipeline. It is safe to call it
+// from several goroutines at once.
+func (p *AppendPipeline) Producer() *Producer {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	producer := &Producer{b: New()}
+	p.producers = append(p.producers, producer)
+	return producer
+}
+
+// Bitmap returns a new bitmap with all the positions set by every producer.
+// It must only be called once all producers are done.
+func (p *AppendPipeline) Bitmap() *Bitmap {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	bitmaps := make([]*Bitmap, len(p.producers))
+	for i, producer := range p.producers {
+		bitmaps[i] = producer.b
+	}
+
+	if len(bitmaps) == 1 {
+		return bitmaps[0].Clone()
+	}
+	return orMany(bitmaps...)
+}
+
+// Producer sets positions on behalf of a single goroutine in an
+// AppendPipeline. It must not be used from several goroutines at once.
+type Producer struct {
+	b *Bitmap
+}
+
+// Set sets to 1 the bit at the given position. Like in Bitmap.Set, positions
+// must be given in ascending order, but only relative to the other positions
+// set with the same producer.
+func (p *Producer) Set(pos int64) error {
+	return p.b.Set(pos)
+}
@@ -0,0 +1,78 @@
+package ewah
+
+import (
+	"math/rand"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAppendPipeline(t *testing.T) {
+	require := require.New(t)
+
+	pipeline := NewAppendPipeline()
+	r := rand.New(rand.NewSource(1))
+
+	var all []int64
+	var chunks [][]int64
+	for i := int64(0); i < 8; i++ {
+		ps := randomPositions(r, 5000, 0.5)
+		for j := range ps {
+			ps[j] += i * 3000
+		}
+		chunks = append(chunks, ps)
+		all = append(all, ps...)
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(chunks))
+	for i, ps := range chunks {
+		wg.Add(1)
+		go func(i int, ps []int64) {
+			defer wg.Done()
+			producer := pipeline.Producer()
+			for _, p := range ps {
+				if err := producer.Set(p); err != nil {
+					errs[i] = err
+					return
+				}
+			}
+		}(i, ps)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		require.NoError(err)
+	}
+
+	expected := make(map[int64]bool)
+	for _, p := range all {
+		expected[p] = true
+	}
+
+	var unique []int64
+	for p := range expected {
+		unique = append(unique, p)
+	}
+	sort.Slice(unique, func(i, j int) bool { return unique[i] < unique[j] })
+
+	require.Equal(unique, positions(pipeline.Bitmap()))
+}
+
+func TestAppendPipelineSingleProducer(t *testing.T) {
+	require := require.New(t)
+
+	pipeline := NewAppendPipeline()
+	require.Equal(New(), pipeline.Bitmap())
+
+	producer := pipeline.Producer()
+	require.NoError(producer.Set(5))
+	require.Equal(ErrInvalidBitSet, producer.Set(1))
+
+	result := pipeline.Bitmap()
+	require.Equal([]int64{5}, positions(result))
+	require.NoError(result.Set(10))
+	require.NoError(producer.Set(6))
+}
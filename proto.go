@@ -0,0 +1,178 @@
+package ewah
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// protoVersion is the version of the format of the data in the protobuf
+// messages.
+const protoVersion = 1
+
+// Field numbers of the Bitmap message defined in ewah.proto.
+const (
+	protoFieldVersion   = 1
+	protoFieldByteOrder = 2
+	protoFieldData      = 3
+)
+
+// Protobuf wire types.
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+// MarshalProto encodes the bitmap as a protobuf Bitmap message, as defined
+// in ewah.proto, with its words written in the given byte order, which must
+// be binary.BigEndian or binary.LittleEndian. The message can be decoded
+// with UnmarshalProto or with code generated from ewah.proto.
+func (b *Bitmap) MarshalProto(order binary.ByteOrder) ([]byte, error) {
+	var byteOrder uint64
+	switch order {
+	case binary.BigEndian:
+	case binary.LittleEndian:
+		byteOrder = 1
+	default:
+		return nil, fmt.Errorf("bitmap: unsupported byte order %s", order)
+	}
+
+	var data bytes.Buffer
+	if _, err := b.Write(&data, order); err != nil {
+		return nil, err
+	}
+
+	buf := appendProtoVarint(nil, protoFieldVersion, protoVersion)
+	if byteOrder != 0 {
+		buf = appendProtoVarint(buf, protoFieldByteOrder, byteOrder)
+	}
+	buf = appendUvarint(buf, protoFieldData<<3|wireBytes)
+	buf = appendUvarint(buf, uint64(data.Len()))
+	return append(buf, data.Bytes()...), nil
+}
+
+// UnmarshalProto decodes a bitmap from a protobuf Bitmap message, as
+// defined in ewah.proto. Unknown fields are ignored.
+func UnmarshalProto(msg []byte) (*Bitmap, error) {
+	var version, byteOrder uint64
+	var data []byte
+	for len(msg) > 0 {
+		key, n := binary.Uvarint(msg)
+		if n <= 0 {
+			return nil, errors.New("bitmap: invalid protobuf field key")
+		}
+		msg = msg[n:]
+
+		var value uint64
+		var bytesValue []byte
+		switch key & 7 {
+		case wireVarint:
+			value, n = binary.Uvarint(msg)
+			if n <= 0 {
+				return nil, fmt.Errorf("bitmap: invalid protobuf varint in field %d", key>>3)
+			}
+		case wireFixed64:
+			n = 8
+		case wireFixed32:
+			n = 4
+		case wireBytes:
+			size, m := binary.Uvarint(msg)
+			if m <= 0 || size > uint64(len(msg)-m) {
+				return nil, fmt.Errorf("bitmap: invalid protobuf length in field %d", key>>3)
+			}
+			bytesValue = msg[m : m+int(size)]
+			n = m + int(size)
+		default:
+			return nil, fmt.Errorf("bitmap: unsupported protobuf wire type %d in field %d", key&7, key>>3)
+		}
+
+		if n > len(msg) {
+			return nil, fmt.Errorf("bitmap: truncated protobuf field %d", key>>3)
+		}
+		msg = msg[n:]
+
+		switch key {
+		case protoFieldVersion<<3 | wireVarint:
+			version = value
+		case protoFieldByteOrder<<3 | wireVarint:
+			byteOrder = value
+		case protoFieldData<<3 | wireBytes:
+			data = bytesValue
+		}
+	}
+
+	if version != protoVersion {
+		return nil, fmt.Errorf("bitmap: unsupported protobuf format version %d", version)
+	}
+
+	var order binary.ByteOrder
+	switch byteOrder {
+	case 0:
+		order = binary.BigEndian
+	case 1:
+		order = binary.LittleEndian
+	default:
+		return nil, fmt.Errorf("bitmap: unknown byte order %d", byteOrder)
+	}
+
+	return FromBytes(data, order)
+}
+
+// appendProtoVarint appends a varint field with the given number and value
+// to buf.
+func appendProtoVarint(buf []byte, field, value uint64) []byte {
+	buf = appendUvarint(buf, field<<3|wireVarint)
+	return appendUvarint(buf, value)
+}
+
+// GRPCCodec is a gRPC codec, which can be registered with
+// encoding.RegisterCodec, that sends *Bitmap values as the protobuf Bitmap
+// messages of MarshalProto, so bitmaps can be used directly as requests and
+// responses of RPCs using the "ewah" content subtype.
+type GRPCCodec struct {
+	// Order is the byte order of the words. Big endian is used if it's nil.
+	Order binary.ByteOrder
+}
+
+// Marshal encodes v, which must be a *Bitmap.
+func (c GRPCCodec) Marshal(v interface{}) ([]byte, error) {
+	b, ok := v.(*Bitmap)
+	if !ok {
+		return nil, fmt.Errorf("bitmap: can't marshal %T, only *Bitmap", v)
+	}
+
+	order := c.Order
+	if order == nil {
+		order = binary.BigEndian
+	}
+	return b.MarshalProto(order)
+}
+
+// Unmarshal decodes data into v, which must be a *Bitmap that is not
+// frozen, replacing its contents.
+func (GRPCCodec) Unmarshal(data []byte, v interface{}) error {
+	b, ok := v.(*Bitmap)
+	if !ok {
+		return fmt.Errorf("bitmap: can't unmarshal into %T, only *Bitmap", v)
+	}
+
+	if b.frozen {
+		return ErrFrozen
+	}
+
+	decoded, err := UnmarshalProto(data)
+	if err != nil {
+		return err
+	}
+
+	b.replace(decoded)
+	return nil
+}
+
+// Name returns the name of the codec, "ewah".
+func (GRPCCodec) Name() string {
+	return "ewah"
+}
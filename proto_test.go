@@ -0,0 +1,97 @@
+package ewah
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProto(t *testing.T) {
+	require := require.New(t)
+	r := rand.New(rand.NewSource(1))
+
+	for _, order := range []binary.ByteOrder{binary.BigEndian, binary.LittleEndian} {
+		for i := 0; i < 10; i++ {
+			b := fromPositions(randomPositions(r, 5000, r.Float64())...)
+			msg, err := b.MarshalProto(order)
+			require.NoError(err)
+
+			result, err := UnmarshalProto(msg)
+			require.NoError(err)
+			require.Equal(b.w, result.w)
+			require.Equal(b.n, result.n)
+		}
+	}
+
+	b := fromPositions(1, 100)
+	var data bytes.Buffer
+	_, err := b.Write(&data, binary.LittleEndian)
+	require.NoError(err)
+
+	msg, err := b.MarshalProto(binary.LittleEndian)
+	require.NoError(err)
+	expected := append([]byte{0x08, 0x01, 0x10, 0x01, 0x1a, byte(data.Len())}, data.Bytes()...)
+	require.Equal(expected, msg)
+
+	// unknown fields of every wire type are skipped
+	unknown := append([]byte{
+		0x20, 0x96, 0x01,
+		0x29, 1, 2, 3, 4, 5, 6, 7, 8,
+		0x32, 0x02, 'h', 'i',
+		0x3d, 1, 2, 3, 4,
+	}, msg...)
+	result, err := UnmarshalProto(unknown)
+	require.NoError(err)
+	require.Equal([]int64{1, 100}, positions(result))
+
+	invalid := [][]byte{
+		{},
+		{0x08, 0x02},
+		{0x08, 0x01, 0x10, 0x05},
+		{0x08, 0x01, 0x1a, 0x10, 0x00},
+		{0x08},
+		{0x0b},
+		msg[:len(msg)-1],
+	}
+	for i, msg := range invalid {
+		_, err := UnmarshalProto(msg)
+		require.Error(err, "message %d", i)
+	}
+
+	_, err = b.MarshalProto(nil)
+	require.Error(err)
+}
+
+func TestGRPCCodec(t *testing.T) {
+	require := require.New(t)
+
+	codec := GRPCCodec{}
+	require.Equal("ewah", codec.Name())
+
+	b := fromPositions(1, 2, 3, 1000)
+	data, err := codec.Marshal(b)
+	require.NoError(err)
+
+	var result Bitmap
+	require.NoError(codec.Unmarshal(data, &result))
+	require.Equal([]int64{1, 2, 3, 1000}, positions(&result))
+
+	_, err = codec.Marshal("bitmap")
+	require.Error(err)
+	require.Error(codec.Unmarshal(data, new(int)))
+	require.Error(codec.Unmarshal([]byte{0x08, 0x07}, &result))
+
+	// bitmaps with contents are replaced
+	other := fromPositions(5, 6000)
+	require.NoError(codec.Unmarshal(data, other))
+	require.Equal([]int64{1, 2, 3, 1000}, positions(other))
+	require.NoError(other.Validate())
+
+	frozen := fromPositions(7)
+	frozen.Freeze()
+	require.Equal(ErrFrozen, codec.Unmarshal(data, frozen))
+	require.Equal([]int64{7}, positions(frozen))
+}
@@ -0,0 +1,320 @@
+package ewah
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Query is a parsed boolean expression over named bitmaps, such as
+// `a AND (b OR NOT c)`. Operators are case insensitive and, from highest to
+// lowest precedence, are NOT, AND and OR. Parenthesis can be used to group
+// expressions. Any other word is the name of a bitmap.
+type Query struct {
+	root queryNode
+}
+
+// ParseQuery parses the given boolean expression.
+func ParseQuery(expr string) (*Query, error) {
+	p := &queryParser{tokens: tokenizeQuery(expr)}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, fmt.Errorf("bitmap: unexpected %q at position %d in query", tok.text, tok.pos)
+	}
+
+	return &Query{root}, nil
+}
+
+// Eval evaluates the query against the given bitmaps and returns the result
+// as a new bitmap. NOT is evaluated within a universe as big as the largest
+// of the given bitmaps. It is an error to reference a bitmap that does not
+// exist.
+func (q *Query) Eval(bitmaps map[string]*Bitmap) (*Bitmap, error) {
+	env := &queryEnv{bitmaps: bitmaps}
+	for _, b := range bitmaps {
+		env.universe = maxInt64(env.universe, b.n)
+	}
+
+	result, err := q.root.eval(env)
+	if err != nil {
+		return nil, err
+	}
+
+	// never hand out one of the bitmaps in the collection
+	if _, ok := q.root.(queryName); ok {
+		result = result.Clone()
+	}
+
+	return result, nil
+}
+
+// String returns the query with every operation enclosed in parenthesis.
+func (q *Query) String() string {
+	return q.root.String()
+}
+
+// Eval parses the given boolean expression and evaluates it against the
+// given bitmaps. See Query for more details.
+func Eval(expr string, bitmaps map[string]*Bitmap) (*Bitmap, error) {
+	q, err := ParseQuery(expr)
+	if err != nil {
+		return nil, err
+	}
+	return q.Eval(bitmaps)
+}
+
+type queryEnv struct {
+	bitmaps  map[string]*Bitmap
+	universe int64
+}
+
+type queryNode interface {
+	eval(env *queryEnv) (*Bitmap, error)
+	String() string
+}
+
+type queryName string
+
+func (n queryName) eval(env *queryEnv) (*Bitmap, error) {
+	b, ok := env.bitmaps[string(n)]
+	if !ok {
+		return nil, fmt.Errorf("bitmap: unknown bitmap %q in query", string(n))
+	}
+	return b, nil
+}
+
+func (n queryName) String() string {
+	return string(n)
+}
+
+type queryNot struct {
+	node queryNode
+}
+
+func (n queryNot) eval(env *queryEnv) (*Bitmap, error) {
+	b, err := n.node.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	return not(b, env.universe), nil
+}
+
+func (n queryNot) String() string {
+	return "NOT " + n.node.String()
+}
+
+type queryAnd []queryNode
+
+// eval intersects all the operands at once and removes the negated ones
+// from the result, so no complement has to be computed unless all operands
+// are negated.
+func (n queryAnd) eval(env *queryEnv) (*Bitmap, error) {
+	var pos, neg []*Bitmap
+	for _, node := range n {
+		var err error
+		var b *Bitmap
+		if not, ok := node.(queryNot); ok {
+			if b, err = not.node.eval(env); err == nil {
+				neg = append(neg, b)
+			}
+		} else if b, err = node.eval(env); err == nil {
+			pos = append(pos, b)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(pos) == 0 {
+		return not(orMany(neg...), env.universe), nil
+	}
+
+	result := andMany(pos...)
+	if len(neg) > 0 {
+		result = andNot(result, orMany(neg...))
+	}
+	return result, nil
+}
+
+func (n queryAnd) String() string {
+	return joinQueryNodes(n, " AND ")
+}
+
+type queryOr []queryNode
+
+func (n queryOr) eval(env *queryEnv) (*Bitmap, error) {
+	bitmaps := make([]*Bitmap, len(n))
+	for i, node := range n {
+		b, err := node.eval(env)
+		if err != nil {
+			return nil, err
+		}
+		bitmaps[i] = b
+	}
+
+	return orMany(bitmaps...), nil
+}
+
+func (n queryOr) String() string {
+	return joinQueryNodes(n, " OR ")
+}
+
+func joinQueryNodes(nodes []queryNode, sep string) string {
+	parts := make([]string, len(nodes))
+	for i, n := range nodes {
+		parts[i] = n.String()
+	}
+	return "(" + strings.Join(parts, sep) + ")"
+}
+
+type tokenKind byte
+
+const (
+	tokenEOF tokenKind = iota
+	tokenName
+	tokenAnd
+	tokenOr
+	tokenNot
+	tokenOpen
+	tokenClose
+)
+
+type queryToken struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func tokenizeQuery(expr string) []queryToken {
+	var tokens []queryToken
+	for i := 0; i < len(expr); {
+		switch c := rune(expr[i]); {
+		case unicode.IsSpace(c):
+			i++
+		case c == '(':
+			tokens = append(tokens, queryToken{tokenOpen, "(", i})
+			i++
+		case c == ')':
+			tokens = append(tokens, queryToken{tokenClose, ")", i})
+			i++
+		default:
+			start := i
+			for i < len(expr) && !unicode.IsSpace(rune(expr[i])) && expr[i] != '(' && expr[i] != ')' {
+				i++
+			}
+
+			word := expr[start:i]
+			kind := tokenName
+			switch strings.ToUpper(word) {
+			case "AND":
+				kind = tokenAnd
+			case "OR":
+				kind = tokenOr
+			case "NOT":
+				kind = tokenNot
+			}
+			tokens = append(tokens, queryToken{kind, word, start})
+		}
+	}
+
+	return append(tokens, queryToken{tokenEOF, "end of query", len(expr)})
+}
+
+type queryParser struct {
+	tokens []queryToken
+	pos    int
+}
+
+func (p *queryParser) peek() queryToken {
+	return p.tokens[p.pos]
+}
+
+func (p *queryParser) next() queryToken {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokenEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *queryParser) parseOr() (queryNode, error) {
+	var nodes queryOr
+	for {
+		node, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+
+		if or, ok := node.(queryOr); ok {
+			nodes = append(nodes, or...)
+		} else {
+			nodes = append(nodes, node)
+		}
+
+		if p.peek().kind != tokenOr {
+			break
+		}
+		p.next()
+	}
+
+	if len(nodes) == 1 {
+		return nodes[0], nil
+	}
+	return nodes, nil
+}
+
+func (p *queryParser) parseAnd() (queryNode, error) {
+	var nodes queryAnd
+	for {
+		node, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+
+		if and, ok := node.(queryAnd); ok {
+			nodes = append(nodes, and...)
+		} else {
+			nodes = append(nodes, node)
+		}
+
+		if p.peek().kind != tokenAnd {
+			break
+		}
+		p.next()
+	}
+
+	if len(nodes) == 1 {
+		return nodes[0], nil
+	}
+	return nodes, nil
+}
+
+func (p *queryParser) parseUnary() (queryNode, error) {
+	switch tok := p.next(); tok.kind {
+	case tokenName:
+		return queryName(tok.text), nil
+	case tokenNot:
+		node, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return queryNot{node}, nil
+	case tokenOpen:
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+
+		if tok := p.next(); tok.kind != tokenClose {
+			return nil, fmt.Errorf("bitmap: expecting \")\" at position %d in query, found %q", tok.pos, tok.text)
+		}
+		return node, nil
+	default:
+		return nil, fmt.Errorf("bitmap: unexpected %q at position %d in query", tok.text, tok.pos)
+	}
+}
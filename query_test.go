@@ -0,0 +1,113 @@
+package ewah
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseQuery(t *testing.T) {
+	testCases := []struct {
+		expr     string
+		expected string
+	}{
+		{"a", "a"},
+		{"a AND b", "(a AND b)"},
+		{"a and b or c", "((a AND b) OR c)"},
+		{"a AND (b OR NOT c)", "(a AND (b OR NOT c))"},
+		{"a AND (b AND c) AND d", "(a AND b AND c AND d)"},
+		{"NOT NOT a", "NOT NOT a"},
+		{"(a OR b) OR (c OR d)", "(a OR b OR c OR d)"},
+		{"  user:1 OR label-foo  ", "(user:1 OR label-foo)"},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.expr, func(t *testing.T) {
+			q, err := ParseQuery(tt.expr)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, q.String())
+		})
+	}
+}
+
+func TestParseQueryErrors(t *testing.T) {
+	testCases := []struct {
+		expr string
+		err  string
+	}{
+		{"", `bitmap: unexpected "end of query" at position 0 in query`},
+		{"a AND", `bitmap: unexpected "end of query" at position 5 in query`},
+		{"a b", `bitmap: unexpected "b" at position 2 in query`},
+		{"(a OR b", `bitmap: expecting ")" at position 7 in query, found "end of query"`},
+		{"a OR )", `bitmap: unexpected ")" at position 5 in query`},
+		{"a)", `bitmap: unexpected ")" at position 1 in query`},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.expr, func(t *testing.T) {
+			_, err := ParseQuery(tt.expr)
+			require.EqualError(t, err, tt.err)
+		})
+	}
+}
+
+func TestEval(t *testing.T) {
+	bitmaps := map[string]*Bitmap{
+		"a": fromPositions(1, 2, 3, 64, 65, 200),
+		"b": fromPositions(2, 64, 300),
+		"c": fromPositions(3, 65, 66),
+	}
+
+	testCases := []struct {
+		expr     string
+		expected []int64
+	}{
+		{"a", []int64{1, 2, 3, 64, 65, 200}},
+		{"a AND b", []int64{2, 64}},
+		{"a OR b OR c", []int64{1, 2, 3, 64, 65, 66, 200, 300}},
+		{"a AND NOT b", []int64{1, 3, 65, 200}},
+		{"a AND (b OR NOT c)", []int64{1, 2, 64, 200}},
+		{"NOT a AND NOT b AND NOT c", expectedOp(nil, []int64{1, 2, 3, 64, 65, 66, 200, 300}, func(_, y bool) bool { return !y })},
+		{"NOT (a OR c) AND b", []int64{300}},
+		{"a AND b AND c", nil},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.expr, func(t *testing.T) {
+			result, err := Eval(tt.expr, bitmaps)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, positions(result))
+		})
+	}
+}
+
+func TestEvalNotUniverse(t *testing.T) {
+	require := require.New(t)
+
+	bitmaps := map[string]*Bitmap{
+		"a": fromPositions(1),
+		"b": fromPositions(5),
+	}
+
+	result, err := Eval("NOT a", bitmaps)
+	require.NoError(err)
+	require.Equal([]int64{0, 2, 3, 4, 5}, positions(result))
+	require.Equal(int64(6), result.n)
+}
+
+func TestEvalDoesNotShareBitmaps(t *testing.T) {
+	require := require.New(t)
+
+	a := fromPositions(1, 2)
+	result, err := Eval("a", map[string]*Bitmap{"a": a})
+	require.NoError(err)
+	require.NotSame(a, result)
+
+	require.NoError(result.Set(3))
+	require.Equal([]int64{1, 2}, positions(a))
+}
+
+func TestEvalUnknownBitmap(t *testing.T) {
+	_, err := Eval("a AND missing", map[string]*Bitmap{"a": New()})
+	require.EqualError(t, err, `bitmap: unknown bitmap "missing" in query`)
+}
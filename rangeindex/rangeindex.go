@@ -0,0 +1,99 @@
+// Package rangeindex implements range-encoded bitmap indexes, which index
+// a column of ordered values with one bitmap per value boundary so any
+// range query is answered combining at most two of them.
+package rangeindex
+
+import (
+	"fmt"
+
+	ewah "github.com/erizocosmico/go-ewah"
+)
+
+// Index is a range-encoded bitmap index over a column of values from 0 to
+// the cardinality of the index minus one. For every value v but the last,
+// it keeps a bitmap with the rows whose value is less than or equal to v,
+// plus a bitmap with all the rows that have a value.
+type Index struct {
+	// le holds, for every value v, the rows with a value <= v.
+	le []*ewah.Bitmap
+	// rows holds all the rows with a value.
+	rows *ewah.Bitmap
+}
+
+// New creates an empty index for values from 0 to cardinality-1.
+func New(cardinality int) *Index {
+	if cardinality < 1 {
+		cardinality = 1
+	}
+
+	le := make([]*ewah.Bitmap, cardinality-1)
+	for i := range le {
+		le[i] = ewah.New()
+	}
+	return &Index{le: le, rows: ewah.New()}
+}
+
+// Cardinality returns the number of distinct values the index can hold.
+func (i *Index) Cardinality() int {
+	return len(i.le) + 1
+}
+
+// Add adds the row with the given value. Like in Bitmap.Set, rows need to be
+// added in ascending order, and every row can only be added once.
+func (i *Index) Add(row int64, value int) error {
+	if value < 0 || value > len(i.le) {
+		return fmt.Errorf("rangeindex: value %d is out of the range [0, %d)", value, i.Cardinality())
+	}
+
+	if err := i.rows.Set(row); err != nil {
+		return err
+	}
+
+	for _, b := range i.le[value:] {
+		if err := b.Set(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LessOrEqual returns the rows whose value is less than or equal to v.
+func (i *Index) LessOrEqual(v int) *ewah.Bitmap {
+	return i.lessOrEqual(v).Clone()
+}
+
+// Less returns the rows whose value is less than v.
+func (i *Index) Less(v int) *ewah.Bitmap {
+	return i.lessOrEqual(v - 1).Clone()
+}
+
+// Greater returns the rows whose value is greater than v.
+func (i *Index) Greater(v int) *ewah.Bitmap {
+	return ewah.AndNotTo(ewah.New(), i.rows, i.lessOrEqual(v))
+}
+
+// GreaterOrEqual returns the rows whose value is greater than or equal to v.
+func (i *Index) GreaterOrEqual(v int) *ewah.Bitmap {
+	return ewah.AndNotTo(ewah.New(), i.rows, i.lessOrEqual(v-1))
+}
+
+// Between returns the rows whose value is in the interval [lo, hi].
+func (i *Index) Between(lo, hi int) *ewah.Bitmap {
+	if lo > hi {
+		return ewah.New()
+	}
+	return ewah.AndNotTo(ewah.New(), i.lessOrEqual(hi), i.lessOrEqual(lo-1))
+}
+
+// lessOrEqual returns the bitmap of the index with the rows whose value is
+// less than or equal to v, which must not be modified.
+func (i *Index) lessOrEqual(v int) *ewah.Bitmap {
+	switch {
+	case v < 0:
+		return ewah.New()
+	case v >= len(i.le):
+		return i.rows
+	default:
+		return i.le[v]
+	}
+}
@@ -0,0 +1,71 @@
+package rangeindex
+
+import (
+	"math/rand"
+	"testing"
+
+	ewah "github.com/erizocosmico/go-ewah"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIndex(t *testing.T) {
+	require := require.New(t)
+	r := rand.New(rand.NewSource(1))
+
+	const cardinality = 10
+	idx := New(cardinality)
+	require.Equal(cardinality, idx.Cardinality())
+
+	values := map[int64]int{}
+	for row := int64(0); row < 5000; row++ {
+		// some rows have no value
+		if r.Intn(5) == 0 {
+			continue
+		}
+
+		v := r.Intn(cardinality)
+		values[row] = v
+		require.NoError(idx.Add(row, v))
+	}
+
+	require.Error(idx.Add(6000, cardinality))
+	require.Error(idx.Add(6000, -1))
+	require.Error(idx.Add(10, 1))
+
+	rows := func(pred func(v int) bool) []int64 {
+		var result []int64
+		for row := int64(0); row < 5000; row++ {
+			if v, ok := values[row]; ok && pred(v) {
+				result = append(result, row)
+			}
+		}
+		return result
+	}
+
+	for v := -1; v <= cardinality; v++ {
+		v := v
+		require.Equal(rows(func(x int) bool { return x <= v }), positions(idx.LessOrEqual(v)), "<= %d", v)
+		require.Equal(rows(func(x int) bool { return x < v }), positions(idx.Less(v)), "< %d", v)
+		require.Equal(rows(func(x int) bool { return x > v }), positions(idx.Greater(v)), "> %d", v)
+		require.Equal(rows(func(x int) bool { return x >= v }), positions(idx.GreaterOrEqual(v)), ">= %d", v)
+
+		for hi := v - 1; hi <= cardinality; hi++ {
+			hi := hi
+			require.Equal(rows(func(x int) bool { return x >= v && x <= hi }), positions(idx.Between(v, hi)), "between %d and %d", v, hi)
+		}
+	}
+
+	// results are copies
+	require.NoError(idx.LessOrEqual(cardinality).Set(10000))
+	require.Nil(positions(idx.Greater(cardinality - 1)))
+	require.Equal(len(values), len(positions(idx.GreaterOrEqual(0))))
+}
+
+func positions(b *ewah.Bitmap) []int64 {
+	var result []int64
+	it := b.IteratorRange(0, int64(b.Bits()))
+	for it.HasNext() {
+		result = append(result, it.Next())
+	}
+	return result
+}
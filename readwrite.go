@@ -0,0 +1,86 @@
+package ewah
+
+import (
+	"encoding/binary"
+	"io"
+)
+
+// WriteTo implements io.WriterTo, writing the bitmap like Write does, in big
+// endian, the same as git uses. Use WithByteOrder to write it in any other
+// byte order.
+func (b *Bitmap) WriteTo(w io.Writer) (int64, error) {
+	return b.Write(w, binary.BigEndian)
+}
+
+// ReadFrom implements io.ReaderFrom, reading a bitmap in big endian like
+// FromReader does and replacing the contents of b with it. It returns the
+// number of bytes read, even if the bitmap could not be read. Frozen bitmaps
+// can't be read into and return ErrFrozen. Use WithByteOrder to read it in
+// any other byte order.
+func (b *Bitmap) ReadFrom(r io.Reader) (int64, error) {
+	return b.readFrom(r, binary.BigEndian)
+}
+
+func (b *Bitmap) readFrom(r io.Reader, order binary.ByteOrder) (int64, error) {
+	if b.frozen {
+		return 0, ErrFrozen
+	}
+
+	cr := &countingReader{r: r}
+	decoded, err := FromReader(cr, order)
+	if err != nil {
+		return cr.n, err
+	}
+
+	b.replace(decoded)
+	return cr.n, nil
+}
+
+// OrderedBitmap is a bitmap whose WriteTo and ReadFrom use a byte order
+// other than big endian.
+type OrderedBitmap struct {
+	*Bitmap
+	Order binary.ByteOrder
+}
+
+// WithByteOrder returns the bitmap wrapped so that it implements io.WriterTo
+// and io.ReaderFrom using the given byte order.
+func (b *Bitmap) WithByteOrder(order binary.ByteOrder) OrderedBitmap {
+	return OrderedBitmap{b, order}
+}
+
+// WriteTo implements io.WriterTo, writing the bitmap like Write does, in the
+// byte order of o.
+func (o OrderedBitmap) WriteTo(w io.Writer) (int64, error) {
+	return o.Write(w, o.Order)
+}
+
+// ReadFrom implements io.ReaderFrom, reading a bitmap in the byte order of o
+// like Bitmap.ReadFrom does.
+func (o OrderedBitmap) ReadFrom(r io.Reader) (int64, error) {
+	return o.readFrom(r, o.Order)
+}
+
+// countingReader counts the bytes read from r.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// countingWriter counts the bytes written to w.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
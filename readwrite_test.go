@@ -0,0 +1,90 @@
+package ewah
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math/rand"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteToReadFrom(t *testing.T) {
+	require := require.New(t)
+	r := rand.New(rand.NewSource(1))
+
+	var _ io.WriterTo = New()
+	var _ io.ReaderFrom = New()
+
+	for i := 0; i < 10; i++ {
+		b := fromPositions(randomPositions(r, 5000, r.Float64())...)
+
+		var buf bytes.Buffer
+		n, err := b.WriteTo(&buf)
+		require.NoError(err)
+		require.Equal(int64(buf.Len()), n)
+
+		var expected bytes.Buffer
+		_, err = b.Write(&expected, binary.BigEndian)
+		require.NoError(err)
+		require.Equal(expected.Bytes(), buf.Bytes())
+
+		result := fromPositions(1, 2, 3)
+		n, err = result.ReadFrom(bufio.NewReader(&buf))
+		require.NoError(err)
+		require.Equal(int64(expected.Len()), n)
+		require.Equal(b.w, result.w)
+		require.Equal(b.n, result.n)
+	}
+
+	b := fromPositions(5, 700)
+	var buf bytes.Buffer
+	_, err := b.WriteTo(&buf)
+	require.NoError(err)
+	data := buf.Bytes()
+
+	result := New()
+	n, err := result.ReadFrom(bufio.NewReader(bytes.NewReader(data[:10])))
+	require.Error(err)
+	require.Equal(int64(10), n)
+
+	n, err = result.ReadFrom(bytes.NewReader(data[:10]))
+	require.Error(err)
+	require.Equal(int64(8), n)
+
+	// headers claiming more words than the input holds are rejected before
+	// reading or allocating any of them
+	huge := []byte{0, 0, 0, 0xff, 0xff, 0xff, 0xff, 0xff, 0, 0, 0, 0}
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	n, err = New().ReadFrom(bytes.NewReader(huge))
+	runtime.ReadMemStats(&after)
+	require.True(errors.Is(err, io.ErrUnexpectedEOF))
+	require.Equal(int64(8), n)
+	require.True(after.TotalAlloc-before.TotalAlloc < 1<<16, "allocated %d bytes", after.TotalAlloc-before.TotalAlloc)
+
+	frozen := New()
+	frozen.Freeze()
+	_, err = frozen.ReadFrom(bytes.NewReader(data))
+	require.Equal(ErrFrozen, err)
+
+	buf.Reset()
+	_, err = b.WithByteOrder(binary.LittleEndian).WriteTo(&buf)
+	require.NoError(err)
+	little, err := FromBytes(buf.Bytes(), binary.LittleEndian)
+	require.NoError(err)
+	require.Equal([]int64{5, 700}, positions(little))
+
+	read := New()
+	n, err = read.WithByteOrder(binary.LittleEndian).ReadFrom(&buf)
+	require.NoError(err)
+	require.Equal(little.ewahSize(), n)
+	require.Equal([]int64{5, 700}, positions(read))
+
+	_, err = frozen.WithByteOrder(binary.LittleEndian).ReadFrom(&buf)
+	require.Equal(ErrFrozen, err)
+}
@@ -0,0 +1,150 @@
+package ewah
+
+import (
+	"container/heap"
+	"math/bits"
+	"sort"
+)
+
+// Region is a range of positions [Start, End) of a bitmap and the number of
+// set bits in it.
+type Region struct {
+	Start, End int64
+	Count      int64
+}
+
+// DensestRegions splits the positions of the bitmap in consecutive windows
+// of the given width, starting at 0, and returns the n windows with most set
+// bits, sorted from the densest to the sparsest. Windows with the same
+// number of set bits are sorted by position. Windows without set bits are
+// never returned.
+func (b *Bitmap) DensestRegions(n int, width int64) []Region {
+	if n <= 0 || width <= 0 {
+		return nil
+	}
+
+	top := &regionHeap{}
+	cur := regionCounter{width: width, window: -1, top: top, n: n}
+
+	var offset int64
+	it := b.runs()
+	for !it.done() {
+		if it.run > 0 && it.bit {
+			cur.addRun(offset, offset+it.run*64)
+		}
+		offset += it.run * 64
+
+		for i := 0; i < it.lits; i++ {
+			cur.addWord(offset, it.literal(i))
+			offset += 64
+		}
+
+		it.discard(it.run + int64(it.lits))
+	}
+	cur.flush()
+
+	if top.Len() == 0 {
+		return nil
+	}
+
+	result := make([]Region, top.Len())
+	copy(result, *top)
+	sort.Slice(result, func(i, j int) bool {
+		return top.better(result[i], result[j])
+	})
+	return result
+}
+
+// regionCounter counts the set bits of consecutive windows, keeping the
+// densest ones in a heap.
+type regionCounter struct {
+	width  int64
+	window int64
+	count  int64
+	top    *regionHeap
+	n      int
+}
+
+func (c *regionCounter) add(window, count int64) {
+	if window != c.window {
+		c.flush()
+		c.window, c.count = window, 0
+	}
+	c.count += count
+}
+
+func (c *regionCounter) flush() {
+	if c.count > 0 {
+		c.push(Region{c.window * c.width, (c.window + 1) * c.width, c.count})
+	}
+	c.count = 0
+}
+
+func (c *regionCounter) push(r Region) {
+	if c.top.Len() < c.n {
+		heap.Push(c.top, r)
+	} else if c.top.better(r, (*c.top)[0]) {
+		(*c.top)[0] = r
+		heap.Fix(c.top, 0)
+	}
+}
+
+// addRun adds all positions in [start, end).
+func (c *regionCounter) addRun(start, end int64) {
+	for start < end {
+		w := start / c.width
+		wend := (w + 1) * c.width
+		if start == w*c.width && wend <= end {
+			// whole windows all have the same count, so only the first n
+			// can make it to the top
+			full := (end - start) / c.width
+			c.flush()
+			for i := int64(0); i < full && i < int64(c.n); i++ {
+				c.push(Region{(w + i) * c.width, (w + i + 1) * c.width, c.width})
+			}
+			start += full * c.width
+			c.window = -1
+			continue
+		}
+
+		take := minInt64(end, wend) - start
+		c.add(w, take)
+		start += take
+	}
+}
+
+// addWord adds the set bits of the literal word starting at offset.
+func (c *regionCounter) addWord(offset int64, word uint64) {
+	for start, end := offset, offset+64; start < end; {
+		w := start / c.width
+		wend := minInt64((w+1)*c.width, end)
+		mask := allones >> uint64(start-offset) &^ (allones >> uint64(wend-offset))
+		if count := bits.OnesCount64(word & mask); count > 0 {
+			c.add(w, int64(count))
+		}
+		start = wend
+	}
+}
+
+// regionHeap is a min-heap of regions, whose first element is the sparsest.
+type regionHeap []Region
+
+// better reports whether a is denser than b, or as dense but located first.
+func (h regionHeap) better(a, b Region) bool {
+	if a.Count != b.Count {
+		return a.Count > b.Count
+	}
+	return a.Start < b.Start
+}
+
+func (h regionHeap) Len() int            { return len(h) }
+func (h regionHeap) Less(i, j int) bool  { return h.better(h[j], h[i]) }
+func (h regionHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *regionHeap) Push(x interface{}) { *h = append(*h, x.(Region)) }
+
+func (h *regionHeap) Pop() interface{} {
+	old := *h
+	r := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return r
+}
@@ -0,0 +1,60 @@
+package ewah
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDensestRegions(t *testing.T) {
+	require := require.New(t)
+
+	b := newBitmap()
+	require.Equal([]Region{
+		{7 * 64, 8 * 64, 64},
+		{9 * 64, 10 * 64, 64},
+		{8 * 64, 9 * 64, 59},
+	}, b.DensestRegions(3, 64))
+
+	require.Equal([]Region{
+		{500, 600, 95},
+		{400, 500, 53},
+		{600, 700, 40},
+	}, b.DensestRegions(3, 100))
+
+	require.Nil(b.DensestRegions(0, 64))
+	require.Nil(b.DensestRegions(3, 0))
+	require.Empty(New().DensestRegions(3, 64))
+}
+
+func TestDensestRegionsRandom(t *testing.T) {
+	require := require.New(t)
+	r := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 30; i++ {
+		ps := randomPositions(r, int64(r.Intn(20000)), r.Float64())
+		b := fromPositions(ps...)
+		width := int64(r.Intn(300) + 1)
+		n := r.Intn(10) + 1
+
+		counts := make(map[int64]int64)
+		for _, p := range ps {
+			counts[p/width]++
+		}
+
+		var expected []Region
+		for w, c := range counts {
+			expected = append(expected, Region{w * width, (w + 1) * width, c})
+		}
+		sort.Slice(expected, func(i, j int) bool {
+			return regionHeap(nil).better(expected[i], expected[j])
+		})
+		if len(expected) > n {
+			expected = expected[:n]
+		}
+
+		require.Equal(expected, b.DensestRegions(n, width))
+	}
+}
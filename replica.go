@@ -0,0 +1,181 @@
+package ewah
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// Replica is a bitmap with a version that increases with every change, which
+// can be kept in sync with the replicas of other peers exchanging Deltas.
+// The peer making changes calls Update, which returns the delta to send to
+// the rest, and the other peers call Apply with the deltas they receive, in
+// order. It is safe to use a Replica from several goroutines.
+type Replica struct {
+	mu      sync.Mutex
+	b       *Bitmap
+	version uint64
+}
+
+// NewReplica creates a replica with the given bitmap and version, which is
+// usually 0 for new replicas, or the version of the peer a full copy of the
+// bitmap was taken from.
+func NewReplica(b *Bitmap, version uint64) *Replica {
+	return &Replica{b: b, version: version}
+}
+
+// Version returns the current version of the replica.
+func (r *Replica) Version() uint64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.version
+}
+
+// Bitmap returns the current bitmap of the replica, which must not be
+// modified, and its version.
+func (r *Replica) Bitmap() (*Bitmap, uint64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.b, r.version
+}
+
+// Update replaces the bitmap of the replica with b, increases its version
+// and returns the delta that takes the replicas of other peers from the
+// previous version to the new one.
+func (r *Replica) Update(b *Bitmap) *Delta {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	diff := xor(r.b, b)
+	// apply the difference like the other peers will, so all replicas end
+	// up with exactly the same words
+	r.b = xor(r.b, diff)
+	r.version++
+
+	return &Delta{
+		From:     r.version - 1,
+		To:       r.version,
+		Checksum: r.b.checksum(),
+		Diff:     diff,
+	}
+}
+
+// Apply applies a delta received from another peer. It returns a
+// *DivergenceError, leaving the replica untouched, if the delta was not
+// computed from the current version of the replica or the result does not
+// match the bitmap of the peer that computed it.
+func (r *Replica) Apply(d *Delta) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if d.From != r.version {
+		return &DivergenceError{Version: r.version, From: d.From}
+	}
+
+	b := xor(r.b, d.Diff)
+	if b.checksum() != d.Checksum {
+		return &DivergenceError{Version: r.version, From: d.From}
+	}
+
+	r.b, r.version = b, d.To
+	return nil
+}
+
+// DivergenceError is returned when a delta can't be applied to a replica.
+type DivergenceError struct {
+	// Version is the version of the replica.
+	Version uint64
+	// From is the version the delta was computed from.
+	From uint64
+}
+
+func (e *DivergenceError) Error() string {
+	if e.Version != e.From {
+		return fmt.Sprintf("bitmap: replica is at version %d, but delta is from version %d", e.Version, e.From)
+	}
+	return fmt.Sprintf("bitmap: replica at version %d has diverged from its peer", e.Version)
+}
+
+// Delta is the change between two versions of a replica. Diff has the
+// positions that changed, and Checksum is a hash of the bitmap once the
+// change is applied, used to detect replicas that have diverged.
+type Delta struct {
+	From, To uint64
+	Checksum uint64
+	Diff     *Bitmap
+}
+
+// Write writes the delta to w as its versions and checksum followed by the
+// difference, in the format of Bitmap.Write.
+func (d *Delta) Write(w io.Writer, order binary.ByteOrder) (int64, error) {
+	var written int64
+	for _, v := range []uint64{d.From, d.To, d.Checksum} {
+		if err := writeUint64(w, order, v); err != nil {
+			return written, err
+		}
+		written += 8
+	}
+
+	n, err := d.Diff.Write(w, order)
+	return written + n, err
+}
+
+// ReadDelta reads a delta written by Delta.Write from r.
+func ReadDelta(r io.Reader, order binary.ByteOrder) (*Delta, error) {
+	var header [3]uint64
+	for i := range header {
+		v, err := readUint64(r, order)
+		if err != nil {
+			return nil, fmt.Errorf("bitmap: can't read delta header: %s", err)
+		}
+		header[i] = v
+	}
+
+	diff, err := FromReader(r, order)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Delta{From: header[0], To: header[1], Checksum: header[2], Diff: diff}, nil
+}
+
+// checksum returns a hash of the bits of the bitmap which, unlike hash, does
+// not depend on how they are encoded.
+func (b *Bitmap) checksum() uint64 {
+	h := mix64(uint64(b.n))
+	var bit bool
+	var run int64
+	flush := func() {
+		if run > 0 {
+			h = mix64(h ^ mix64(uint64(run)<<1|uint64(fill(bit)&1)))
+			run = 0
+		}
+	}
+
+	it := b.runs()
+	for !it.done() {
+		if it.run > 0 {
+			if it.bit != bit {
+				flush()
+			}
+			bit, run = it.bit, run+it.run
+			it.discard(it.run)
+			continue
+		}
+
+		if word := it.literal(0); word == 0 || word == allones {
+			if (word == allones) != bit {
+				flush()
+			}
+			bit, run = word == allones, run+1
+		} else {
+			flush()
+			h = mix64(h ^ word)
+		}
+		it.discard(1)
+	}
+
+	flush()
+	return h
+}
@@ -0,0 +1,91 @@
+package ewah
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplica(t *testing.T) {
+	require := require.New(t)
+	r := rand.New(rand.NewSource(1))
+
+	initial := fromPositions(randomPositions(r, 10000, 0.5)...)
+	leader := NewReplica(initial, 0)
+	follower := NewReplica(initial.Clone(), 0)
+
+	var deltas []*Delta
+	for i := 0; i < 10; i++ {
+		b := NewWithBase(int64(r.Intn(2000)))
+		for _, p := range randomPositions(r, 10000+int64(i)*100, r.Float64()) {
+			if p >= b.Base() {
+				require.NoError(b.Set(p))
+			}
+		}
+
+		d := leader.Update(b)
+		require.Equal(uint64(i), d.From)
+		require.Equal(uint64(i+1), d.To)
+
+		// deltas go through the wire
+		var buf bytes.Buffer
+		_, err := d.Write(&buf, binary.LittleEndian)
+		require.NoError(err)
+
+		d, err = ReadDelta(&buf, binary.LittleEndian)
+		require.NoError(err)
+		require.NoError(follower.Apply(d))
+		deltas = append(deltas, d)
+
+		bitmap, version := follower.Bitmap()
+		require.Equal(uint64(i+1), version)
+		require.Equal(positions(b), positions(bitmap))
+	}
+
+	var err *DivergenceError
+	require.True(errors.As(follower.Apply(deltas[3]), &err))
+	require.Equal(&DivergenceError{Version: 10, From: 3}, err)
+	require.Equal("bitmap: replica is at version 10, but delta is from version 3", err.Error())
+
+	// a replica that has the right version but different bits
+	b, _ := leader.Bitmap()
+	b = b.Clone()
+	require.NoError(b.Set(b.n + 10))
+	diverged := NewReplica(b, 10)
+
+	d := leader.Update(fromPositions(1, 2, 3))
+	require.Equal(&DivergenceError{Version: 10, From: 10}, diverged.Apply(d))
+	require.Equal(uint64(10), diverged.Version())
+	require.NoError(follower.Apply(d))
+	require.Equal(uint64(11), follower.Version())
+
+	_, err2 := ReadDelta(bytes.NewReader([]byte{1, 2, 3}), binary.LittleEndian)
+	require.Error(err2)
+
+	// only the bytes written are counted
+	for _, size := range []int{0, 8, 20} {
+		w := &shortWriter{left: size}
+		n, err := d.Write(w, binary.LittleEndian)
+		require.Error(err, "size %d", size)
+		require.Equal(int64(w.written), n, "size %d", size)
+	}
+}
+
+// shortWriter fails once left bytes have been written.
+type shortWriter struct {
+	left, written int
+}
+
+func (w *shortWriter) Write(p []byte) (int, error) {
+	if len(p) > w.left {
+		return 0, errors.New("short write")
+	}
+
+	w.left -= len(p)
+	w.written += len(p)
+	return len(p), nil
+}
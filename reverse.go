@@ -0,0 +1,44 @@
+package ewah
+
+import "math/bits"
+
+// ReverseBits returns a new bitmap with the same number of bits where the
+// bit at position i is the bit at position Bits()-1-i of the bitmap. Runs
+// are copied as a whole, without looking at every bit in them.
+func (b *Bitmap) ReverseBits() *Bitmap {
+	// segments of the bitmap in order: a run of count clean words of bit,
+	// or a single literal if count is zero
+	type segment struct {
+		bit   bool
+		count int64
+		word  uint64
+	}
+
+	var segments []segment
+	it := b.runs()
+	for !it.done() {
+		if it.run > 0 {
+			segments = append(segments, segment{bit: it.bit, count: it.run})
+			it.discard(it.run)
+			continue
+		}
+
+		segments = append(segments, segment{word: it.literal(0)})
+		it.discard(1)
+	}
+
+	// the last word is padded with zeroes that must not be copied
+	skip := wordsFor(b.n)*64 - b.n
+	s := newStreamBuilder()
+	for i := len(segments) - 1; i >= 0; i-- {
+		seg := segments[i]
+		if seg.count > 0 {
+			s.addRun(seg.bit, seg.count*64-skip)
+		} else {
+			s.addBits(bits.Reverse64(seg.word)<<uint64(skip), int(64-skip))
+		}
+		skip = 0
+	}
+
+	return s.bitmap()
+}
@@ -0,0 +1,46 @@
+package ewah
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReverseBits(t *testing.T) {
+	require := require.New(t)
+	r := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 50; i++ {
+		b := New()
+		if i%2 == 0 {
+			b = NewWithBase(int64(r.Intn(3000)))
+		}
+		for _, p := range randomPositions(r, 5000, r.Float64()) {
+			if p >= b.Base() {
+				require.NoError(b.Set(p))
+			}
+		}
+		if i%3 == 0 {
+			require.NoError(b.Truncate(b.n - int64(r.Intn(100))))
+		}
+
+		var expected []int64
+		ps := positions(b)
+		for j := len(ps) - 1; j >= 0; j-- {
+			expected = append(expected, b.n-1-ps[j])
+		}
+
+		rev := b.ReverseBits()
+		require.Equal(b.n, rev.n)
+		require.Equal(expected, positions(rev))
+		requireSameBits(t, b, rev.ReverseBits())
+	}
+
+	b := New()
+	for i := int64(0); i < 64*3; i++ {
+		require.NoError(b.Set(i))
+	}
+	require.Equal([]uint64{uint64(newRlw(true, 3, 0))}, b.ReverseBits().w)
+	require.Equal(int64(0), New().ReverseBits().n)
+}
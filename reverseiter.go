@@ -0,0 +1,116 @@
+package ewah
+
+import "math/bits"
+
+// ReverseIterator walks the positions of the set bits of a bitmap in
+// descending order. The bitmap must not be modified while it is being
+// iterated.
+type ReverseIterator struct {
+	w []uint64
+	// markers holds the index and the position of the first bit of every
+	// marker, as they can't be found walking the words backwards.
+	markers []reverseMarker
+	// m is the index of the current marker and lits the number of its
+	// literals left.
+	m, lits int
+	// start and pos delimit the positions left in the current run of ones.
+	start, pos int64
+	// word holds the bits left in the current literal, which starts at
+	// wordOffset.
+	word       uint64
+	wordOffset int64
+
+	next    int64
+	has     bool
+	fetched bool
+}
+
+type reverseMarker struct {
+	idx    int
+	offset int64
+}
+
+// ReverseIterator returns an iterator over the positions of all the set bits
+// of the bitmap, from the highest to the lowest. The markers are located
+// first, in a single pass that skips their literals, and then the words are
+// walked backwards.
+func (b *Bitmap) ReverseIterator() *ReverseIterator {
+	var markers []reverseMarker
+	offset := b.base
+	for i := 0; i < len(b.w); i += int(rlw(b.w[i]).l()) + 1 {
+		markers = append(markers, reverseMarker{i, offset})
+		offset += (int64(rlw(b.w[i]).k()) + int64(rlw(b.w[i]).l())) * 64
+	}
+
+	it := &ReverseIterator{w: b.w, markers: markers, m: len(markers) - 1}
+	if it.m >= 0 {
+		it.lits = it.literals(it.m)
+	}
+	return it
+}
+
+// literals returns the number of literals of the marker at index m.
+func (it *ReverseIterator) literals(m int) int {
+	idx := it.markers[m].idx
+	l := int(rlw(it.w[idx]).l())
+	if rest := len(it.w) - idx - 1; l > rest {
+		return rest
+	}
+	return l
+}
+
+// HasNext reports whether there are positions left.
+func (it *ReverseIterator) HasNext() bool {
+	if !it.fetched {
+		it.next, it.has = it.advance()
+		it.fetched = true
+	}
+	return it.has
+}
+
+// Next returns the next position, or -1 if there are none left.
+func (it *ReverseIterator) Next() int64 {
+	if !it.HasNext() {
+		return -1
+	}
+
+	it.fetched = false
+	return it.next
+}
+
+// advance returns the next set position, or false if there are none left.
+func (it *ReverseIterator) advance() (int64, bool) {
+	for {
+		if it.word != 0 {
+			idx := 63 - bits.TrailingZeros64(it.word)
+			it.word &^= bmask >> uint(idx)
+			return it.wordOffset + int64(idx), true
+		}
+
+		if it.pos > it.start {
+			it.pos--
+			return it.pos, true
+		}
+
+		if it.m < 0 {
+			return 0, false
+		}
+
+		marker := it.markers[it.m]
+		r := rlw(it.w[marker.idx])
+		if it.lits > 0 {
+			it.lits--
+			it.word = it.w[marker.idx+1+it.lits]
+			it.wordOffset = marker.offset + (int64(r.k())+int64(it.lits))*64
+			continue
+		}
+
+		if r.b() {
+			it.start, it.pos = marker.offset, marker.offset+int64(r.k())*64
+		}
+
+		if it.m--; it.m >= 0 {
+			it.lits = it.literals(it.m)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package ewah
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReverseIterator(t *testing.T) {
+	require := require.New(t)
+	r := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 30; i++ {
+		b := NewWithBase(int64(r.Intn(5000)))
+		var ps []int64
+		for _, p := range randomPositions(r, int64(r.Intn(20000)), r.Float64()) {
+			if p >= b.Base() {
+				require.NoError(b.Set(p))
+				ps = append(ps, p)
+			}
+		}
+
+		var result []int64
+		it := b.ReverseIterator()
+		for it.HasNext() {
+			result = append(result, it.Next())
+		}
+		require.Equal(int64(-1), it.Next())
+
+		require.Len(result, len(ps))
+		for j, p := range result {
+			require.Equal(ps[len(ps)-1-j], p)
+		}
+	}
+
+	it := zeroes(1000).Not().ReverseIterator()
+	require.Equal(int64(999), it.Next())
+	require.Equal(int64(998), it.Next())
+
+	require.False(New().ReverseIterator().HasNext())
+}
@@ -0,0 +1,93 @@
+package ewah
+
+// RLWCursor walks the encoded words of a bitmap one running length word
+// (RLW) at a time, exposing the run of clean words of every RLW and the
+// literal words that follow it, so custom algorithms can work on the
+// compressed form directly. Literal words hold 64 bits each, the first of
+// them in the most significant bit. The bitmap must not be modified while
+// the cursor is in use.
+//
+//	c := b.RLWCursor()
+//	for c.Next() {
+//		// c.Offset(), c.Bit(), c.Run(), c.Literals()...
+//	}
+type RLWCursor struct {
+	w []uint64
+	// i is the index of the current RLW, or -1 before the first call to Next.
+	i int
+	// offset is the position of the first bit covered by the current RLW.
+	offset int64
+	// next is the offset of the RLW after the current one.
+	next int64
+}
+
+// RLWCursor returns a cursor positioned before the first RLW of the bitmap.
+func (b *Bitmap) RLWCursor() *RLWCursor {
+	return &RLWCursor{w: b.w, i: -1, next: b.base}
+}
+
+// Segments returns a cursor over the segments of the bitmap, each of them a
+// RLW with its run and the literal words that follow it. It is the same as
+// RLWCursor.
+func (b *Bitmap) Segments() *RLWCursor {
+	return b.RLWCursor()
+}
+
+// Next moves to the next RLW, reporting whether there is one.
+func (c *RLWCursor) Next() bool {
+	switch {
+	case c.i >= len(c.w):
+		return false
+	case c.i >= 0:
+		c.i += int(rlw(c.w[c.i]).l()) + 1
+	default:
+		c.i = 0
+	}
+
+	if c.i >= len(c.w) {
+		c.i = len(c.w)
+		return false
+	}
+
+	c.offset = c.next
+	c.next += (c.Run() + int64(c.Literals())) * 64
+	return true
+}
+
+// Offset returns the position of the first bit covered by the current RLW.
+// All the positions before the offset of the first RLW are unset.
+func (c *RLWCursor) Offset() int64 {
+	return c.offset
+}
+
+// Bit returns the value of the clean words of the current RLW.
+func (c *RLWCursor) Bit() bool {
+	return rlw(c.w[c.i]).b()
+}
+
+// Run returns the number of clean words of the current RLW.
+func (c *RLWCursor) Run() int64 {
+	return int64(rlw(c.w[c.i]).k())
+}
+
+// Literals returns the number of literal words that follow the clean words
+// of the current RLW.
+func (c *RLWCursor) Literals() int {
+	l := int(rlw(c.w[c.i]).l())
+	// a corrupt RLW may have more literals than words
+	if c.i+1+l > len(c.w) {
+		l = len(c.w) - c.i - 1
+	}
+	return l
+}
+
+// Literal returns the i-th literal word of the current RLW.
+func (c *RLWCursor) Literal(i int) uint64 {
+	return c.w[c.i+1+i]
+}
+
+// LiteralWords returns all the literal words of the current RLW, without
+// copying them. They must not be modified.
+func (c *RLWCursor) LiteralWords() []uint64 {
+	return c.w[c.i+1 : c.i+1+c.Literals()]
+}
@@ -0,0 +1,71 @@
+package ewah
+
+import (
+	"math/bits"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRLWCursor(t *testing.T) {
+	require := require.New(t)
+
+	c := newBitmap().RLWCursor()
+	require.True(c.Next())
+	require.Equal(int64(0), c.Offset())
+	require.False(c.Bit())
+	require.Equal(int64(5), c.Run())
+	require.Equal(2, c.Literals())
+	require.Equal(uint64(1)<<6, c.Literal(1))
+	require.Equal([]uint64{1 << 5, 1 << 6}, c.LiteralWords())
+
+	require.True(c.Next())
+	require.Equal(int64(7*64), c.Offset())
+	require.True(c.Bit())
+	require.Equal(int64(1), c.Run())
+	require.Equal(1, c.Literals())
+
+	require.True(c.Next())
+	require.Equal(int64(9*64), c.Offset())
+	require.Equal(0, c.Literals())
+	require.Empty(c.LiteralWords())
+
+	require.False(c.Next())
+	require.False(c.Next())
+	require.False(New().RLWCursor().Next())
+
+	require.Equal(newBitmap().RLWCursor(), newBitmap().Segments())
+	require.False(New().Segments().Next())
+
+	// rebuild the positions of random bitmaps from their RLWs
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 20; i++ {
+		b := NewWithBase(int64(r.Intn(2000)))
+		for _, p := range randomPositions(r, 8000, r.Float64()) {
+			if p >= b.Base() {
+				require.NoError(b.Set(p))
+			}
+		}
+
+		var ps []int64
+		c := b.RLWCursor()
+		for c.Next() {
+			pos := c.Offset()
+			for j := int64(0); j < c.Run()*64; j++ {
+				if c.Bit() {
+					ps = append(ps, pos)
+				}
+				pos++
+			}
+
+			for _, word := range c.LiteralWords() {
+				for ; word != 0; word &^= bmask >> uint(bits.LeadingZeros64(word)) {
+					ps = append(ps, pos+int64(bits.LeadingZeros64(word)))
+				}
+				pos += 64
+			}
+		}
+		require.Equal(positions(b), ps)
+	}
+}
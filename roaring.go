@@ -0,0 +1,336 @@
+package ewah
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/bits"
+)
+
+const (
+	roaringCookie      = 12347
+	roaringCookieNoRun = 12346
+	// roaringNoOffsetThreshold is the number of containers below which
+	// bitmaps with run containers have no offsets in the header.
+	roaringNoOffsetThreshold = 4
+	// roaringMaxArray is the maximum cardinality of array containers.
+	roaringMaxArray = 4096
+	// roaringChunkWords is the number of words covered by every container.
+	roaringChunkWords = 1 << 16 / 64
+)
+
+type roaringKind byte
+
+const (
+	roaringArray roaringKind = iota
+	roaringBitmap
+	roaringRun
+)
+
+// roaringContainer describes a container of a roaring bitmap.
+type roaringContainer struct {
+	key  uint16
+	card int
+	runs int
+	kind roaringKind
+}
+
+// size returns the number of bytes of the serialized container.
+func (c roaringContainer) size() int {
+	switch c.kind {
+	case roaringArray:
+		return 2 * c.card
+	case roaringRun:
+		return 2 + 4*c.runs
+	default:
+		return roaringChunkWords * 8
+	}
+}
+
+// WriteRoaring writes the bitmap in the portable serialization format of
+// Roaring bitmaps, so it can be read by any Roaring implementation, and
+// returns the number of bytes written. Every container is encoded straight
+// from the words covering it, choosing the smallest of the array, bitmap
+// and run encodings. The format can only hold positions lower than 2^32.
+func (b *Bitmap) WriteRoaring(w io.Writer) (int64, error) {
+	var containers []roaringContainer
+	var hasRuns bool
+	var err error
+	b.eachChunk(func(key int64, words []uint64) bool {
+		if key > 0xffff {
+			err = fmt.Errorf("bitmap: position %d does not fit in the 32 bits of the roaring format", key<<16)
+			return false
+		}
+
+		c := roaringContainer{key: uint16(key)}
+		var prev uint64
+		for _, word := range words {
+			c.card += bits.OnesCount64(word)
+			c.runs += bits.OnesCount64(word &^ (word>>1 | prev<<63))
+			prev = word
+		}
+
+		c.kind = roaringBitmap
+		if c.card <= roaringMaxArray {
+			c.kind = roaringArray
+		}
+
+		if run := (roaringContainer{kind: roaringRun, runs: c.runs}); run.size() < c.size() {
+			c.kind = roaringRun
+			hasRuns = true
+		}
+
+		containers = append(containers, c)
+		return true
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	header := roaringHeader(containers, hasRuns)
+	if _, err := w.Write(header); err != nil {
+		return 0, err
+	}
+	n := int64(len(header))
+
+	var buf []byte
+	i := 0
+	b.eachChunk(func(key int64, words []uint64) bool {
+		buf = containers[i].append(buf[:0], words)
+		i++
+
+		if _, err = w.Write(buf); err != nil {
+			return false
+		}
+		n += int64(len(buf))
+		return true
+	})
+
+	return n, err
+}
+
+// roaringHeader returns the header of a roaring bitmap with the given
+// containers.
+func roaringHeader(containers []roaringContainer, hasRuns bool) []byte {
+	size := len(containers)
+	var header []byte
+	if hasRuns {
+		header = appendUint32LE(header, uint32(roaringCookie|(size-1)<<16))
+		runs := make([]byte, (size+7)/8)
+		for i, c := range containers {
+			if c.kind == roaringRun {
+				runs[i/8] |= 1 << uint(i%8)
+			}
+		}
+		header = append(header, runs...)
+	} else {
+		header = appendUint32LE(header, roaringCookieNoRun)
+		header = appendUint32LE(header, uint32(size))
+	}
+
+	for _, c := range containers {
+		header = appendUint16LE(header, c.key)
+		header = appendUint16LE(header, uint16(c.card-1))
+	}
+
+	if !hasRuns || size >= roaringNoOffsetThreshold {
+		offset := len(header) + 4*size
+		for _, c := range containers {
+			header = appendUint32LE(header, uint32(offset))
+			offset += c.size()
+		}
+	}
+
+	return header
+}
+
+// append appends the serialized container with the given words to buf.
+func (c roaringContainer) append(buf []byte, words []uint64) []byte {
+	switch c.kind {
+	case roaringArray:
+		for i, word := range words {
+			for ; word != 0; word &^= bmask >> uint(bits.LeadingZeros64(word)) {
+				buf = appendUint16LE(buf, uint16(i*64+bits.LeadingZeros64(word)))
+			}
+		}
+	case roaringRun:
+		buf = appendUint16LE(buf, uint16(c.runs))
+		start := -1
+		for i := 0; i <= len(words)*64; i++ {
+			set := i < len(words)*64 && words[i/64]&(bmask>>uint(i%64)) != 0
+			if set && start < 0 {
+				start = i
+			} else if !set && start >= 0 {
+				buf = appendUint16LE(buf, uint16(start))
+				buf = appendUint16LE(buf, uint16(i-start-1))
+				start = -1
+			}
+		}
+	default:
+		for _, word := range words {
+			buf = appendUint64LE(buf, bits.Reverse64(word))
+		}
+	}
+	return buf
+}
+
+// eachChunk calls fn with the words of every chunk of 2^16 bits that has
+// any bit set, in ascending order, until fn returns false. key is the index
+// of the chunk. words must not be retained by fn.
+func (b *Bitmap) eachChunk(fn func(key int64, words []uint64) bool) {
+	var chunk [roaringChunkWords]uint64
+	key, nonzero := int64(0), false
+	flush := func() bool {
+		if !nonzero {
+			return true
+		}
+
+		nonzero = false
+		ok := fn(key, chunk[:])
+		chunk = [roaringChunkWords]uint64{}
+		return ok
+	}
+
+	it := b.runs()
+	var word int64
+	for !it.done() {
+		if it.run > 0 && !it.bit {
+			word += it.run
+			it.discard(it.run)
+			continue
+		}
+
+		if word/roaringChunkWords != key {
+			if !flush() {
+				return
+			}
+			key = word / roaringChunkWords
+		}
+
+		if it.run > 0 {
+			chunk[word%roaringChunkWords] = allones
+		} else {
+			chunk[word%roaringChunkWords] = it.literal(0)
+		}
+		nonzero = nonzero || chunk[word%roaringChunkWords] != 0
+		word++
+		it.discard(1)
+	}
+
+	flush()
+}
+
+// FromRoaring reads a bitmap in the portable serialization format of
+// Roaring bitmaps. The result has as many bits as needed to hold its last
+// set position.
+func FromRoaring(r io.Reader) (*Bitmap, error) {
+	d := roaringReader{r: r}
+	cookie := d.uint32()
+	var size int
+	var runs []byte
+	switch {
+	case d.err != nil:
+	case cookie&0xffff == roaringCookie:
+		size = int(cookie>>16) + 1
+		runs = d.bytes((size + 7) / 8)
+	case cookie == roaringCookieNoRun:
+		size = int(d.uint32())
+	default:
+		return nil, fmt.Errorf("bitmap: unknown roaring cookie %d", cookie)
+	}
+
+	if d.err == nil && size > 1<<16 {
+		return nil, fmt.Errorf("bitmap: roaring bitmap with %d containers, more than possible", size)
+	}
+
+	header := d.bytes(4 * size)
+	if runs == nil || size >= roaringNoOffsetThreshold {
+		d.bytes(4 * size)
+	}
+
+	s := newStreamBuilder()
+	for i := 0; i < size && d.err == nil; i++ {
+		key := int64(binary.LittleEndian.Uint16(header[i*4:]))
+		card := int(binary.LittleEndian.Uint16(header[i*4+2:])) + 1
+		base := key << 16
+		if base < s.n {
+			return nil, fmt.Errorf("bitmap: roaring container %d is out of order", key)
+		}
+
+		switch {
+		case runs != nil && runs[i/8]&(1<<uint(i%8)) != 0:
+			for n := int(d.uint16()); n > 0 && d.err == nil; n-- {
+				start, length := base+int64(d.uint16()), int64(d.uint16())+1
+				if start < s.n || start+length > base+1<<16 {
+					return nil, fmt.Errorf("bitmap: invalid run in roaring container %d", key)
+				}
+				s.addRun(false, start-s.n)
+				s.addRun(true, length)
+			}
+		case card <= roaringMaxArray:
+			for ; card > 0 && d.err == nil; card-- {
+				pos := base + int64(d.uint16())
+				if pos < s.n {
+					return nil, fmt.Errorf("bitmap: unsorted values in roaring container %d", key)
+				}
+				s.addRun(false, pos-s.n)
+				s.addRun(true, 1)
+			}
+		default:
+			data := d.bytes(roaringChunkWords * 8)
+			last := roaringChunkWords - 1
+			for last >= 0 && d.err == nil && binary.LittleEndian.Uint64(data[last*8:]) == 0 {
+				last--
+			}
+
+			s.addRun(false, base-s.n)
+			for j := 0; j <= last; j++ {
+				word := bits.Reverse64(binary.LittleEndian.Uint64(data[j*8:]))
+				count := 64
+				if j == last {
+					count -= bits.TrailingZeros64(word)
+				}
+				s.addBits(word, count)
+			}
+		}
+	}
+
+	if d.err != nil {
+		return nil, fmt.Errorf("bitmap: can't read roaring bitmap: %s", d.err)
+	}
+	return s.bitmap(), nil
+}
+
+// roaringReader reads little endian values, keeping the first error.
+type roaringReader struct {
+	r   io.Reader
+	err error
+}
+
+func (d *roaringReader) bytes(n int) []byte {
+	buf := make([]byte, n)
+	if d.err == nil {
+		_, d.err = io.ReadFull(d.r, buf)
+	}
+	return buf
+}
+
+func (d *roaringReader) uint16() uint16 {
+	return binary.LittleEndian.Uint16(d.bytes(2))
+}
+
+func (d *roaringReader) uint32() uint32 {
+	return binary.LittleEndian.Uint32(d.bytes(4))
+}
+
+func appendUint16LE(buf []byte, v uint16) []byte {
+	return append(buf, byte(v), byte(v>>8))
+}
+
+func appendUint32LE(buf []byte, v uint32) []byte {
+	return append(buf, byte(v), byte(v>>8), byte(v>>16), byte(v>>24))
+}
+
+func appendUint64LE(buf []byte, v uint64) []byte {
+	return appendUint32LE(appendUint32LE(buf, uint32(v)), uint32(v>>32))
+}
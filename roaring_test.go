@@ -0,0 +1,88 @@
+package ewah
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteRoaring(t *testing.T) {
+	require := require.New(t)
+
+	var buf bytes.Buffer
+	n, err := fromPositions(1, 2, 1<<16+5).WriteRoaring(&buf)
+	require.NoError(err)
+	require.Equal(int64(buf.Len()), n)
+	require.Equal([]byte{
+		0x3a, 0x30, 0, 0, 2, 0, 0, 0,
+		0, 0, 1, 0, 1, 0, 0, 0,
+		24, 0, 0, 0, 28, 0, 0, 0,
+		1, 0, 2, 0,
+		5, 0,
+	}, buf.Bytes())
+
+	b := New()
+	for i := int64(0); i < 100; i++ {
+		require.NoError(b.Set(i))
+	}
+
+	buf.Reset()
+	_, err = b.WriteRoaring(&buf)
+	require.NoError(err)
+	require.Equal([]byte{
+		0x3b, 0x30, 0, 0, 1,
+		0, 0, 99, 0,
+		1, 0, 0, 0, 99, 0,
+	}, buf.Bytes())
+
+	buf.Reset()
+	_, err = New().WriteRoaring(&buf)
+	require.NoError(err)
+	require.Equal([]byte{0x3a, 0x30, 0, 0, 0, 0, 0, 0}, buf.Bytes())
+
+	_, err = fromPositions(1 << 32).WriteRoaring(&buf)
+	require.EqualError(err, "bitmap: position 4294967296 does not fit in the 32 bits of the roaring format")
+}
+
+func TestRoaringRoundTrip(t *testing.T) {
+	require := require.New(t)
+	r := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 20; i++ {
+		ps := randomPositions(r, 300000, r.Float64())
+		// a dense chunk with many runs, encoded as a bitmap container
+		for p := int64(400000); p < 420000; p += 2 {
+			ps = append(ps, p)
+		}
+		b := fromPositions(ps...)
+
+		var buf bytes.Buffer
+		_, err := b.WriteRoaring(&buf)
+		require.NoError(err)
+
+		b2, err := FromRoaring(&buf)
+		require.NoError(err)
+		require.Equal(b.n, b2.n)
+		require.Equal(positions(b), positions(b2))
+	}
+}
+
+func TestFromRoaringErrors(t *testing.T) {
+	require := require.New(t)
+
+	_, err := FromRoaring(bytes.NewReader([]byte{1, 2, 3, 4}))
+	require.EqualError(err, "bitmap: unknown roaring cookie 67305985")
+
+	_, err = FromRoaring(bytes.NewReader([]byte{0x3a, 0x30, 0, 0, 2, 0, 0, 0}))
+	require.EqualError(err, "bitmap: can't read roaring bitmap: EOF")
+
+	_, err = FromRoaring(bytes.NewReader([]byte{
+		0x3a, 0x30, 0, 0, 2, 0, 0, 0,
+		1, 0, 0, 0, 0, 0, 0, 0,
+		24, 0, 0, 0, 26, 0, 0, 0,
+		1, 0, 2, 0,
+	}))
+	require.EqualError(err, "bitmap: roaring container 0 is out of order")
+}
@@ -0,0 +1,21 @@
+package ewah
+
+// Rotate returns a new bitmap with the same number of bits where every bit
+// at position i of the bitmap is moved to position (i+n) mod Bits(), so
+// positive values of n rotate towards the end and negative ones towards the
+// start. When n is a multiple of 64, whole words are copied.
+func (b *Bitmap) Rotate(n int64) *Bitmap {
+	s := newStreamBuilder()
+	if b.n == 0 {
+		return s.bitmap()
+	}
+
+	n %= b.n
+	if n < 0 {
+		n += b.n
+	}
+
+	s.addSlice(b, b.n-n, b.n)
+	s.addSlice(b, 0, b.n-n)
+	return s.bitmap()
+}
@@ -0,0 +1,47 @@
+package ewah
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRotate(t *testing.T) {
+	require := require.New(t)
+	r := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 50; i++ {
+		b := New()
+		if i%2 == 0 {
+			b = NewWithBase(int64(r.Intn(3000)))
+		}
+		for _, p := range randomPositions(r, 5000, r.Float64()) {
+			if p >= b.Base() {
+				require.NoError(b.Set(p))
+			}
+		}
+
+		n := int64(r.Intn(12000)) - 6000
+		if i%4 == 0 {
+			n = n / 64 * 64
+		}
+
+		expected := make([]bool, b.n)
+		for _, p := range positions(b) {
+			expected[((p+n)%b.n+b.n)%b.n] = true
+		}
+
+		rot := b.Rotate(n)
+		require.Equal(b.n, rot.n)
+		for pos, set := range expected {
+			require.Equal(set, rot.Get(int64(pos)), "rotating %d bits by %d", b.n, n)
+		}
+		requireSameBits(t, b, rot.Rotate(-n))
+	}
+
+	b := fromPositions(0, 64, 65, 191)
+	require.Equal([]int64{63, 64, 128, 129}, positions(b.Rotate(64)))
+	require.Equal([]int64{63, 64, 190, 191}, positions(b.Rotate(-1)))
+	require.Equal(int64(0), New().Rotate(3).n)
+}
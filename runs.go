@@ -0,0 +1,61 @@
+package ewah
+
+import "math/bits"
+
+// Interval is a range of positions [Start, End).
+type Interval struct {
+	Start, End int64
+}
+
+// Runs returns the runs of consecutive set bits of the bitmap as intervals,
+// in ascending order. Runs of ones in the words are joined with the set bits
+// around them, and the runs inside literal words are found by counting their
+// leading zeroes and ones, so the bits are never looked at one by one.
+func (b *Bitmap) Runs() []Interval {
+	var result []Interval
+	start := int64(-1)
+	var offset int64
+	it := b.runs()
+	for !it.done() {
+		if it.run > 0 {
+			if it.bit && start < 0 {
+				start = offset
+			} else if !it.bit && start >= 0 {
+				result = append(result, Interval{start, offset})
+				start = -1
+			}
+
+			offset += it.run * 64
+			it.discard(it.run)
+			continue
+		}
+
+		word := it.literal(0)
+		for i := 0; i < 64; {
+			if start < 0 {
+				if word<<uint(i) == 0 {
+					break
+				}
+
+				i += bits.LeadingZeros64(word << uint(i))
+				start = offset + int64(i)
+			} else {
+				// the zeroes shifted in become ones, which stop the count at
+				// the end of the word
+				i += bits.LeadingZeros64(^(word << uint(i)))
+				if i < 64 {
+					result = append(result, Interval{start, offset + int64(i)})
+					start = -1
+				}
+			}
+		}
+
+		offset += 64
+		it.discard(1)
+	}
+
+	if start >= 0 {
+		result = append(result, Interval{start, offset})
+	}
+	return result
+}
@@ -0,0 +1,41 @@
+package ewah
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRuns(t *testing.T) {
+	require := require.New(t)
+	r := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 30; i++ {
+		b := New()
+		var expected []Interval
+		var pos int64
+		for j := 0; j < 50; j++ {
+			start := pos + int64(r.Intn(200)) + 1
+			end := start + int64(r.Intn(300)) + 1
+			require.NoError(b.SetRange(start, end))
+			expected = append(expected, Interval{start, end})
+			pos = end
+		}
+		require.Equal(expected, b.Runs())
+	}
+
+	ps := randomPositions(r, 10000, 0.5)
+	var result []int64
+	for _, run := range fromPositions(ps...).Runs() {
+		require.True(run.Start < run.End)
+		for p := run.Start; p < run.End; p++ {
+			result = append(result, p)
+		}
+	}
+	require.Equal(ps, result)
+
+	require.Equal([]Interval{{0, 1000}}, zeroes(1000).Not().Runs())
+	require.Equal([]Interval{{0, 1}, {63, 65}, {6400, 6401}}, fromPositions(0, 63, 64, 6400).Runs())
+	require.Empty(New().Runs())
+}
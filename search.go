@@ -0,0 +1,79 @@
+package ewah
+
+import "math/bits"
+
+// NextSetBit returns the position of the first set bit at or after pos, or
+// -1 if there is none. Runs of zeroes and literal words before pos are
+// skipped as a whole.
+func (b *Bitmap) NextSetBit(pos int64) int64 {
+	it := bitIterator{it: b.runs()}
+	if pos > 0 {
+		it.seek(pos)
+	}
+
+	if next, ok := it.next(); ok {
+		return next
+	}
+	return -1
+}
+
+// PrevSetBit returns the position of the last set bit at or before pos, or
+// -1 if there is none. As words can only be decoded forwards, the words
+// before pos are walked, but runs are skipped as a whole and literal words
+// are looked at without decoding their bits one by one.
+func (b *Bitmap) PrevSetBit(pos int64) int64 {
+	prev := int64(-1)
+	it := b.runs()
+	var offset int64
+	for !it.done() && offset <= pos {
+		if it.run > 0 {
+			end := offset + it.run*64
+			if it.bit {
+				prev = minInt64(end-1, pos)
+			}
+
+			offset = end
+			it.discard(it.run)
+			continue
+		}
+
+		word := it.literal(0)
+		if pos-offset < 63 {
+			word &= allones << uint(63-(pos-offset))
+		}
+		if word != 0 {
+			prev = offset + 63 - int64(bits.TrailingZeros64(word))
+		}
+
+		offset += 64
+		it.discard(1)
+	}
+	return prev
+}
+
+// Minimum returns the position of the first set bit, or -1 if there is none.
+func (b *Bitmap) Minimum() int64 {
+	return b.NextSetBit(0)
+}
+
+// Maximum returns the position of the last set bit, or -1 if there is none.
+// The words of the last marker are looked at first, backwards, so the
+// previous words only have to be walked when all of them are zeroes.
+func (b *Bitmap) Maximum() int64 {
+	off := b.lastOffset()
+	if off < 0 {
+		return b.PrevSetBit(b.n - 1)
+	}
+
+	r := rlw(b.w[b.lastrlw])
+	for i := int(r.l()); i > 0; i-- {
+		if word := b.w[b.lastrlw+i]; word != 0 {
+			return b.base + off + (int64(r.k())+int64(i))*64 - 1 - int64(bits.TrailingZeros64(word))
+		}
+	}
+
+	if r.b() && r.k() > 0 {
+		return b.base + off + int64(r.k())*64 - 1
+	}
+	return b.PrevSetBit(b.base + off - 1)
+}
@@ -0,0 +1,98 @@
+package ewah
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNextSetBit(t *testing.T) {
+	require := require.New(t)
+	r := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 20; i++ {
+		n := int64(r.Intn(10000))
+		ps := randomPositions(r, n, r.Float64()*r.Float64())
+		b := fromPositions(ps...)
+
+		next := int64(-1)
+		j := len(ps) - 1
+		for pos := n + 10; pos >= -1; pos-- {
+			if j >= 0 && ps[j] == pos {
+				next = pos
+				j--
+			}
+			require.Equal(next, b.NextSetBit(pos), "position %d", pos)
+		}
+	}
+
+	b := NewWithBase(6400)
+	require.NoError(b.Set(6500))
+	require.Equal(int64(6500), b.NextSetBit(0))
+	require.Equal(int64(-1), b.NextSetBit(6501))
+}
+
+func TestPrevSetBit(t *testing.T) {
+	require := require.New(t)
+	r := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 20; i++ {
+		n := int64(r.Intn(10000))
+		ps := randomPositions(r, n, r.Float64()*r.Float64())
+		b := fromPositions(ps...)
+
+		prev := int64(-1)
+		j := 0
+		for pos := int64(-1); pos < n+10; pos++ {
+			if j < len(ps) && ps[j] == pos {
+				prev = pos
+				j++
+			}
+			require.Equal(prev, b.PrevSetBit(pos), "position %d", pos)
+		}
+	}
+
+	b := NewWithBase(6400)
+	require.NoError(b.Set(6500))
+	require.Equal(int64(-1), b.PrevSetBit(6499))
+	require.Equal(int64(6500), b.PrevSetBit(1<<40))
+
+	b = New()
+	for pos := int64(130); pos < 1000; pos++ {
+		require.NoError(b.Set(pos))
+	}
+	require.Equal(int64(-1), b.PrevSetBit(129))
+	require.Equal(int64(500), b.PrevSetBit(500))
+	require.Equal(int64(999), b.PrevSetBit(5000))
+}
+
+func TestMinimumMaximum(t *testing.T) {
+	require := require.New(t)
+	r := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 50; i++ {
+		ps := randomPositions(r, int64(r.Intn(10000)), r.Float64()*r.Float64())
+		b := fromPositions(ps...)
+		if len(ps) == 0 {
+			require.Equal(int64(-1), b.Minimum())
+			require.Equal(int64(-1), b.Maximum())
+			continue
+		}
+
+		require.Equal(ps[0], b.Minimum())
+		require.Equal(ps[len(ps)-1], b.Maximum())
+
+		b.extend(b.n + int64(r.Intn(100000)))
+		require.Equal(ps[len(ps)-1], b.Maximum())
+	}
+
+	require.Equal(int64(-1), New().Minimum())
+	require.Equal(int64(-1), New().Maximum())
+	require.Equal(int64(999), zeroes(1000).Not().Maximum())
+
+	b := NewWithBase(6400)
+	require.NoError(b.Set(6500))
+	require.Equal(int64(6500), b.Minimum())
+	require.Equal(int64(6500), b.Maximum())
+}
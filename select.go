@@ -0,0 +1,77 @@
+package ewah
+
+import (
+	"fmt"
+	"math/bits"
+)
+
+// ToArray returns the positions of all the set bits in ascending order. The
+// bits are counted first, so the result is allocated only once.
+func (b *Bitmap) ToArray() []int64 {
+	result := make([]int64, 0, b.cardinality())
+	b.each(func(pos int64) bool {
+		result = append(result, pos)
+		return true
+	})
+	return result
+}
+
+// Select returns the position of the set bit ranked i, counting from 0, or an
+// error if there are not that many set bits. Runs and literal words are
+// skipped by counting their bits, as SelectRange does.
+func (b *Bitmap) Select(i int64) (int64, error) {
+	var buf [1]int64
+	if i >= 0 {
+		if pos := b.SelectRange(i, i+1, buf[:0]); len(pos) > 0 {
+			return pos[0], nil
+		}
+	}
+	return 0, fmt.Errorf("bitmap: there is no set bit ranked %d", i)
+}
+
+// SelectRange appends to dst the positions of the set bits ranked from from
+// up to, but not including, to, counting from 0, and returns the extended
+// slice. Runs and literal words before from are skipped by counting their
+// bits, so getting a page of positions takes a single pass over the words,
+// which makes it a good fit for offset/limit pagination.
+func (b *Bitmap) SelectRange(from, to int64, dst []int64) []int64 {
+	if from < 0 {
+		from = 0
+	}
+
+	it := b.runs()
+	var offset, rank int64
+	for !it.done() && rank < to {
+		if it.run > 0 {
+			if it.bit {
+				count := it.run * 64
+				for i := maxInt64(from-rank, 0); i < minInt64(count, to-rank); i++ {
+					dst = append(dst, offset+i)
+				}
+				rank += count
+			}
+
+			offset += it.run * 64
+			it.discard(it.run)
+			continue
+		}
+
+		word := it.literal(0)
+		if count := int64(bits.OnesCount64(word)); rank+count <= from {
+			rank += count
+		} else {
+			for ; word != 0 && rank < to; rank++ {
+				idx := bits.LeadingZeros64(word)
+				word &^= bmask >> uint(idx)
+				if rank >= from {
+					dst = append(dst, offset+int64(idx))
+				}
+			}
+		}
+
+		offset += 64
+		it.discard(1)
+	}
+
+	return dst
+}
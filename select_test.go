@@ -0,0 +1,80 @@
+package ewah
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestToArray(t *testing.T) {
+	require := require.New(t)
+	r := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 20; i++ {
+		ps := randomPositions(r, int64(r.Intn(10000)), r.Float64())
+		result := fromPositions(ps...).ToArray()
+		require.Equal(len(ps), cap(result))
+		if len(ps) > 0 {
+			require.Equal(ps, result)
+		}
+	}
+
+	require.Empty(New().ToArray())
+	require.Equal([]int64{6400, 6500}, fromPositions(6400, 6500).ToArray())
+}
+
+func TestSelect(t *testing.T) {
+	require := require.New(t)
+	r := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 20; i++ {
+		ps := randomPositions(r, int64(r.Intn(10000)), r.Float64())
+		b := fromPositions(ps...)
+		for rank, p := range ps {
+			pos, err := b.Select(int64(rank))
+			require.NoError(err)
+			require.Equal(p, pos)
+		}
+
+		_, err := b.Select(int64(len(ps)))
+		require.Error(err)
+	}
+
+	_, err := fromPositions(1).Select(-1)
+	require.Error(err)
+
+	b := NewWithBase(6400)
+	require.NoError(b.Set(6500))
+	pos, err := b.Select(0)
+	require.NoError(err)
+	require.Equal(int64(6500), pos)
+}
+
+func TestSelectRange(t *testing.T) {
+	require := require.New(t)
+	r := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 50; i++ {
+		ps := randomPositions(r, 10000, r.Float64())
+		b := fromPositions(ps...)
+
+		from := int64(r.Intn(len(ps) + 10))
+		to := from + int64(r.Intn(500))
+
+		var expected []int64
+		for rank, p := range ps {
+			if int64(rank) >= from && int64(rank) < to {
+				expected = append(expected, p)
+			}
+		}
+		require.Equal(expected, b.SelectRange(from, to, nil), "[%d, %d)", from, to)
+	}
+
+	b := fromPositions(3, 64, 65, 66, 200)
+	buf := make([]int64, 0, 8)
+	require.Equal([]int64{64, 65, 66}, b.SelectRange(1, 4, buf))
+	require.Equal([]int64{3, 64}, b.SelectRange(-2, 2, buf))
+	require.Empty(b.SelectRange(3, 3, nil))
+	require.Empty(b.SelectRange(5, 10, nil))
+}
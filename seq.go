@@ -0,0 +1,30 @@
+//go:build go1.23
+// +build go1.23
+
+package ewah
+
+import (
+	"iter"
+	"math"
+)
+
+// All returns an iterator over the positions of all the set bits of the
+// bitmap, in ascending order, to be used in range loops. The positions are
+// found lazily as the loop advances.
+func (b *Bitmap) All() iter.Seq[int64] {
+	return b.Between(0, math.MaxInt64)
+}
+
+// Between returns an iterator over the positions of the set bits in the
+// interval [from, to), in ascending order, to be used in range loops. Like
+// IteratorRange, it jumps straight to from.
+func (b *Bitmap) Between(from, to int64) iter.Seq[int64] {
+	return func(yield func(int64) bool) {
+		it := b.IteratorRange(from, to)
+		for it.HasNext() {
+			if !yield(it.Next()) {
+				return
+			}
+		}
+	}
+}
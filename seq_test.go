@@ -0,0 +1,38 @@
+//go:build go1.23
+// +build go1.23
+
+package ewah
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSeq(t *testing.T) {
+	require := require.New(t)
+	r := rand.New(rand.NewSource(1))
+
+	ps := randomPositions(r, 10000, 0.5)
+	b := fromPositions(ps...)
+
+	var result []int64
+	for pos := range b.All() {
+		result = append(result, pos)
+	}
+	require.Equal(ps, result)
+
+	result = nil
+	for pos := range b.Between(1000, 2000) {
+		if pos >= 1500 {
+			break
+		}
+		result = append(result, pos)
+	}
+	require.Equal(iterate(b.IteratorRange(1000, 1500)), result)
+
+	for range New().All() {
+		require.Fail("empty bitmap has no positions")
+	}
+}
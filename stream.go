@@ -0,0 +1,159 @@
+package ewah
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+var errStreamClosed = errors.New("bitmap: stream writer is closed")
+
+// maxPendingLiterals is the number of literal words a StreamWriter keeps in
+// memory before writing them under a marker of their own.
+const maxPendingLiterals = 1024
+
+// StreamWriter encodes a bitmap while its bits are being set, writing every
+// word as soon as it can't change anymore, so bitmaps of any size can be
+// encoded in a single pass using little memory. As the number of bits and
+// words are not known until the end, a placeholder header is written first
+// and fixed up when the writer is closed. The output is in the same format
+// as the one of Write, so it can be read with FromReader.
+type StreamWriter struct {
+	w     io.WriteSeeker
+	order binary.ByteOrder
+	// start is the offset of the header in w.
+	start int64
+	// b holds the words that have not been written yet.
+	b *Bitmap
+	// written is the number of words already written.
+	written int64
+	buf     []byte
+	err     error
+}
+
+// NewStreamWriter creates a StreamWriter that writes a bitmap at the current
+// offset of w.
+func NewStreamWriter(w io.WriteSeeker, order binary.ByteOrder) (*StreamWriter, error) {
+	start, err := w.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, fmt.Errorf("bitmap: can't get offset of the header: %s", err)
+	}
+
+	if _, err := w.Write(make([]byte, 8)); err != nil {
+		return nil, fmt.Errorf("bitmap: can't write header: %s", err)
+	}
+
+	return &StreamWriter{w: w, order: order, start: start, b: New()}, nil
+}
+
+// Set sets the bit at the given position, which must be greater than any
+// other position set before, like Bitmap.Set does.
+func (s *StreamWriter) Set(pos int64) error {
+	if s.err != nil {
+		return s.err
+	}
+
+	if err := s.b.Set(pos); err != nil {
+		return err
+	}
+
+	s.flush()
+	return s.err
+}
+
+// flush writes the pending words that can't change anymore.
+func (s *StreamWriter) flush() {
+	flushPending(s.b, s.write)
+}
+
+// flushPending passes to write the words of b, a bitmap that is being
+// appended to, that can't change anymore, and removes them from b. Only the
+// last marker and its literals are kept, as appending more bits may change
+// them, but once it has too many literals, all but the last one are
+// written. The base of b is moved past the written words, so it is still a
+// valid bitmap on its own.
+func flushPending(b *Bitmap, write func(words ...uint64)) {
+	b.hasCard = false
+	if b.lastrlw > 0 {
+		for i := 0; i < b.lastrlw; i += int(rlw(b.w[i]).l()) + 1 {
+			b.base += (int64(rlw(b.w[i]).k()) + int64(rlw(b.w[i]).l())) * 64
+		}
+
+		write(b.w[:b.lastrlw]...)
+		b.w = b.w[:copy(b.w, b.w[b.lastrlw:])]
+		b.lastrlw, b.prevrlw, b.lastoff = 0, -1, 0
+	}
+
+	if len(b.w) > 0 && rlw(b.w[0]).l() > maxPendingLiterals {
+		r := rlw(b.w[0])
+		last := b.w[len(b.w)-1]
+		r.setl(r.l() - 1)
+		b.w[0] = uint64(r)
+		b.base += (int64(r.k()) + int64(r.l())) * 64
+		write(b.w[:len(b.w)-1]...)
+		b.w = append(b.w[:0], uint64(newRlw(false, 0, 1)), last)
+	}
+}
+
+// write writes the given words to the underlying writer.
+func (s *StreamWriter) write(words ...uint64) {
+	if s.err != nil {
+		return
+	}
+
+	var b [8]byte
+	s.buf = s.buf[:0]
+	for _, word := range words {
+		s.order.PutUint64(b[:], word)
+		s.buf = append(s.buf, b[:]...)
+	}
+
+	if _, err := s.w.Write(s.buf); err != nil {
+		s.err = fmt.Errorf("bitmap: can't write words: %s", err)
+		return
+	}
+	s.written += int64(len(words))
+}
+
+// Close writes the pending words and fixes up the header, leaving the
+// offset of the underlying writer at the end of the bitmap. The writer can't
+// be used after closing it.
+func (s *StreamWriter) Close() error {
+	if s.err != nil {
+		return s.err
+	}
+
+	lastrlw := uint32(s.written) + uint32(s.b.lastrlw)
+	s.write(s.b.w...)
+	if s.err != nil {
+		return s.err
+	}
+	s.err = errStreamClosed
+
+	if err := writeUint32(s.w, s.order, lastrlw); err != nil {
+		return fmt.Errorf("bitmap: can't write position of current RLW: %s", err)
+	}
+
+	end, err := s.w.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return fmt.Errorf("bitmap: can't get offset of the end: %s", err)
+	}
+
+	if _, err := s.w.Seek(s.start, io.SeekStart); err != nil {
+		return fmt.Errorf("bitmap: can't seek to the header: %s", err)
+	}
+
+	if err := writeUint32(s.w, s.order, s.b.Bits()); err != nil {
+		return fmt.Errorf("bitmap: can't write header: %s", err)
+	}
+
+	if err := writeUint32(s.w, s.order, uint32(s.written)); err != nil {
+		return fmt.Errorf("bitmap: can't write header: %s", err)
+	}
+
+	if _, err := s.w.Seek(end, io.SeekStart); err != nil {
+		return fmt.Errorf("bitmap: can't seek to the end: %s", err)
+	}
+	return nil
+}
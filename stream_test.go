@@ -0,0 +1,75 @@
+package ewah
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStreamWriter(t *testing.T) {
+	require := require.New(t)
+	r := rand.New(rand.NewSource(1))
+
+	f, err := ioutil.TempFile("", "ewah")
+	require.NoError(err)
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	_, err = f.Write([]byte("junk"))
+	require.NoError(err)
+
+	s, err := NewStreamWriter(f, binary.BigEndian)
+	require.NoError(err)
+
+	ps := randomPositions(r, 500000, 0.5)
+	// a long run of literals
+	for p := int64(500000); p < 1000000; p += 3 {
+		ps = append(ps, p)
+	}
+	for _, p := range ps {
+		require.NoError(s.Set(p))
+		require.True(len(s.b.w) <= maxPendingLiterals+2)
+	}
+	require.Equal(ErrInvalidBitSet, s.Set(0))
+	require.NoError(s.Set(ps[len(ps)-1]))
+	require.NoError(s.Close())
+	require.Error(s.Set(ps[len(ps)-1] + 1))
+
+	_, err = f.Seek(4, io.SeekStart)
+	require.NoError(err)
+
+	b, err := FromReader(f, binary.BigEndian)
+	require.NoError(err)
+	require.Equal(ps, positions(b))
+	require.Equal(fromPositions(ps...).Bits(), b.Bits())
+
+	// the bitmap can still be modified once read
+	require.NoError(b.Set(b.n + 100))
+	require.True(b.Get(b.n - 1))
+}
+
+func TestStreamWriterEmpty(t *testing.T) {
+	require := require.New(t)
+
+	f, err := ioutil.TempFile("", "ewah")
+	require.NoError(err)
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	s, err := NewStreamWriter(f, binary.LittleEndian)
+	require.NoError(err)
+	require.NoError(s.Close())
+
+	data, err := ioutil.ReadFile(f.Name())
+	require.NoError(err)
+	var buf bytes.Buffer
+	_, err = New().Write(&buf, binary.LittleEndian)
+	require.NoError(err)
+	require.Equal(buf.Bytes(), data)
+}
@@ -0,0 +1,46 @@
+package ewah
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+)
+
+// MarshalText implements encoding.TextMarshaler. The bitmap is written like
+// Write does in big endian byte order, and the result is encoded in
+// standard base64, so it can be embedded in text formats such as JSON, YAML
+// or TOML, or logged.
+func (b *Bitmap) MarshalText() ([]byte, error) {
+	var data bytes.Buffer
+	if _, err := b.Write(&data, binary.BigEndian); err != nil {
+		return nil, err
+	}
+
+	text := make([]byte, base64.StdEncoding.EncodedLen(data.Len()))
+	base64.StdEncoding.Encode(text, data.Bytes())
+	return text, nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, decoding a bitmap
+// encoded by MarshalText and replacing the contents of b with it. Frozen
+// bitmaps can't be unmarshaled into and return ErrFrozen.
+func (b *Bitmap) UnmarshalText(text []byte) error {
+	if b.frozen {
+		return ErrFrozen
+	}
+
+	data := make([]byte, base64.StdEncoding.DecodedLen(len(text)))
+	n, err := base64.StdEncoding.Decode(data, text)
+	if err != nil {
+		return fmt.Errorf("bitmap: can't decode base64 text: %s", err)
+	}
+
+	decoded, err := FromBytes(data[:n], binary.BigEndian)
+	if err != nil {
+		return err
+	}
+
+	b.replace(decoded)
+	return nil
+}
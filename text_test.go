@@ -0,0 +1,49 @@
+package ewah
+
+import (
+	"encoding/json"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestText(t *testing.T) {
+	require := require.New(t)
+	r := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 10; i++ {
+		b := fromPositions(randomPositions(r, 5000, r.Float64())...)
+		text, err := b.MarshalText()
+		require.NoError(err)
+
+		result := fromPositions(1, 2, 3)
+		require.NoError(result.UnmarshalText(text))
+		require.Equal(b.w, result.w)
+		require.Equal(b.n, result.n)
+		require.Equal(positions(b), positions(result))
+	}
+
+	text, err := New().MarshalText()
+	require.NoError(err)
+	require.Equal("AAAAAAAAAAD/////", string(text))
+
+	b := fromPositions(0)
+	text, err = b.MarshalText()
+	require.NoError(err)
+	require.Equal("AAAAAQAAAAIAAAAAAAAAAYAAAAAAAAAAAAAAAA==", string(text))
+
+	// bitmaps can be used as values in text formats
+	data, err := json.Marshal(map[string]*Bitmap{"b": fromPositions(5, 700)})
+	require.NoError(err)
+	var m map[string]*Bitmap
+	require.NoError(json.Unmarshal(data, &m))
+	require.Equal([]int64{5, 700}, positions(m["b"]))
+
+	require.Error(New().UnmarshalText([]byte("not base64!")))
+	require.Error(New().UnmarshalText([]byte("AAAA")))
+
+	frozen := New()
+	frozen.Freeze()
+	require.Equal(ErrFrozen, frozen.UnmarshalText(text))
+}
@@ -0,0 +1,109 @@
+package ewah
+
+import (
+	"encoding/binary"
+	"io"
+)
+
+// Tombstoned is a bitmap of the positions that exist, the base, paired with
+// a bitmap of the ones that have been deleted since, the tombstones, which
+// is how segment-based stores keep track of removed rows without rewriting
+// the base. A position is contained if it is in the base and has not been
+// deleted. Merge folds the deletions into the base once they pile up.
+type Tombstoned struct {
+	base, deleted *Bitmap
+}
+
+// NewTombstoned creates a new pair with the given base and no deletions.
+// The base is owned by the pair from then on.
+func NewTombstoned(base *Bitmap) *Tombstoned {
+	return &Tombstoned{base: base, deleted: New()}
+}
+
+// Base returns the bitmap of the positions that exist, including the
+// deleted ones. It must not be modified.
+func (t *Tombstoned) Base() *Bitmap {
+	return t.base
+}
+
+// Deleted returns the bitmap of the deleted positions. It must not be
+// modified.
+func (t *Tombstoned) Deleted() *Bitmap {
+	return t.deleted
+}
+
+// Add adds the given position to the base, which, like in Bitmap.Set, must
+// be greater than any other position added before.
+func (t *Tombstoned) Add(pos int64) error {
+	return t.base.Set(pos)
+}
+
+// Delete marks the given position as deleted. Positions can be deleted in
+// any order, but deleting one lower than the greatest deleted so far
+// rewrites the tombstones, so deletions are cheaper in ascending order.
+// Deleting a position that is not in the base has no effect.
+func (t *Tombstoned) Delete(pos int64) error {
+	if pos < 0 || !t.base.Get(pos) || t.deleted.Get(pos) {
+		return nil
+	}
+
+	if pos >= t.deleted.n {
+		return t.deleted.Set(pos)
+	}
+
+	if t.deleted.frozen {
+		return ErrFrozen
+	}
+
+	single := New()
+	if err := single.Set(pos); err != nil {
+		return err
+	}
+	t.deleted.replace(or(t.deleted, single))
+	return nil
+}
+
+// Contains reports whether the position is in the base and has not been
+// deleted.
+func (t *Tombstoned) Contains(pos int64) bool {
+	return t.base.Get(pos) && !t.deleted.Get(pos)
+}
+
+// Live returns a new bitmap with the positions that are contained.
+func (t *Tombstoned) Live() *Bitmap {
+	return andNot(t.base, t.deleted)
+}
+
+// Merge removes the deleted positions from the base and clears the
+// tombstones. The base keeps its number of bits, so positions can still be
+// added after the last one.
+func (t *Tombstoned) Merge() {
+	t.base, t.deleted = andNot(t.base, t.deleted), New()
+}
+
+// Write writes the base followed by the tombstones to w, in the format of
+// Bitmap.Write, and returns the number of bytes written.
+func (t *Tombstoned) Write(w io.Writer, order binary.ByteOrder) (int64, error) {
+	n, err := t.base.Write(w, order)
+	if err != nil {
+		return n, err
+	}
+
+	m, err := t.deleted.Write(w, order)
+	return n + m, err
+}
+
+// ReadTombstoned reads a pair written by Tombstoned.Write from r.
+func ReadTombstoned(r io.Reader, order binary.ByteOrder) (*Tombstoned, error) {
+	base, err := FromReader(r, order)
+	if err != nil {
+		return nil, err
+	}
+
+	deleted, err := FromReader(r, order)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Tombstoned{base: base, deleted: deleted}, nil
+}
@@ -0,0 +1,67 @@
+package ewah
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTombstoned(t *testing.T) {
+	require := require.New(t)
+	r := rand.New(rand.NewSource(1))
+
+	ps := randomPositions(r, 5000, 0.6)
+	tb := NewTombstoned(fromPositions(ps[:len(ps)/2]...))
+	for _, p := range ps[len(ps)/2:] {
+		require.NoError(tb.Add(p))
+	}
+
+	deleted := map[int64]bool{}
+	for i := 0; i < 500; i++ {
+		p := int64(r.Intn(5100))
+		deleted[p] = true
+		require.NoError(tb.Delete(p))
+	}
+
+	var live []int64
+	for _, p := range ps {
+		if !deleted[p] {
+			live = append(live, p)
+		}
+	}
+
+	check := func(tb *Tombstoned) {
+		for pos := int64(-1); pos < 5100; pos++ {
+			require.Equal(tb.Base().Get(pos) && !deleted[pos], tb.Contains(pos), "position %d", pos)
+		}
+		require.Equal(live, positions(tb.Live()))
+	}
+	check(tb)
+
+	var buf bytes.Buffer
+	n, err := tb.Write(&buf, binary.BigEndian)
+	require.NoError(err)
+	require.Equal(int64(buf.Len()), n)
+
+	read, err := ReadTombstoned(&buf, binary.BigEndian)
+	require.NoError(err)
+	require.Equal(positions(tb.Deleted()), positions(read.Deleted()))
+	check(read)
+
+	bits := tb.Base().n
+	tb.Merge()
+	require.Equal(live, positions(tb.Base()))
+	require.Equal(bits, tb.Base().n)
+	require.Nil(positions(tb.Deleted()))
+	check(tb)
+
+	require.Equal(ErrInvalidBitSet, tb.Add(0))
+	require.NoError(tb.Add(bits))
+	require.True(tb.Contains(bits))
+
+	_, err = ReadTombstoned(bytes.NewReader(buf.Bytes()[:10]), binary.BigEndian)
+	require.Error(err)
+}
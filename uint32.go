@@ -0,0 +1,37 @@
+package ewah
+
+import "math"
+
+// FromSortedUint32 creates a bitmap with the given positions set, which must
+// be sorted in ascending order, although they may be repeated.
+func FromSortedUint32(positions []uint32) (*Bitmap, error) {
+	b := New()
+	for _, pos := range positions {
+		if err := b.Set(int64(pos)); err != nil {
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+// AddUint32 is like Set, but takes the position as a uint32.
+func (b *Bitmap) AddUint32(pos uint32) error {
+	return b.Set(int64(pos))
+}
+
+// ContainsUint32 is like Get, but takes the position as a uint32.
+func (b *Bitmap) ContainsUint32(pos uint32) bool {
+	return b.Get(int64(pos))
+}
+
+// ToUint32Slice returns the positions of all the set bits that fit in a
+// uint32, in ascending order. Positions greater than math.MaxUint32 are left
+// out.
+func (b *Bitmap) ToUint32Slice() []uint32 {
+	var result []uint32
+	it := b.IteratorRange(0, math.MaxUint32+1)
+	for it.HasNext() {
+		result = append(result, uint32(it.Next()))
+	}
+	return result
+}
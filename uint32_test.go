@@ -0,0 +1,32 @@
+package ewah
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUint32(t *testing.T) {
+	require := require.New(t)
+
+	ids := []uint32{1, 5, 64, 65, 1000, math.MaxUint32 - 1}
+	b, err := FromSortedUint32(ids)
+	require.NoError(err)
+	require.Equal(ids, b.ToUint32Slice())
+
+	require.True(b.ContainsUint32(64))
+	require.False(b.ContainsUint32(63))
+
+	require.NoError(b.AddUint32(math.MaxUint32))
+	require.Equal(ErrInvalidBitSet, b.AddUint32(2))
+	require.True(b.ContainsUint32(math.MaxUint32))
+
+	require.NoError(b.Set(math.MaxUint32 + 10))
+	require.Equal(append(ids, math.MaxUint32), b.ToUint32Slice())
+
+	_, err = FromSortedUint32([]uint32{5, 1})
+	require.Equal(ErrInvalidBitSet, err)
+
+	require.Empty(New().ToUint32Slice())
+}
@@ -0,0 +1,40 @@
+package ewah
+
+// Union is a read-only view of the union of several bitmaps. Queries probe
+// the bitmaps one by one, stopping at the first one with the bit set, so the
+// union is never computed. It is a good fit for answering a few point
+// queries when building the union would be more expensive. The bitmaps must
+// not be modified while the view is in use.
+type Union struct {
+	bitmaps []*Bitmap
+	n       int64
+}
+
+// NewUnion returns a view of the union of the given bitmaps.
+func NewUnion(bitmaps ...*Bitmap) *Union {
+	u := &Union{bitmaps: make([]*Bitmap, len(bitmaps))}
+	copy(u.bitmaps, bitmaps)
+	for _, b := range bitmaps {
+		u.n = maxInt64(u.n, b.n)
+	}
+	return u
+}
+
+// Bits returns the number of bits of the largest bitmap in the union.
+func (u *Union) Bits() uint32 {
+	return uint32(u.n)
+}
+
+// Get returns the bit at the given position, being true 1 and false 0.
+func (u *Union) Get(pos int64) bool {
+	if pos < 0 || pos >= u.n {
+		return false
+	}
+
+	for _, b := range u.bitmaps {
+		if b.Get(pos) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,28 @@
+package ewah
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnion(t *testing.T) {
+	require := require.New(t)
+	r := rand.New(rand.NewSource(1))
+
+	bitmaps := make([]*Bitmap, 5)
+	for i := range bitmaps {
+		bitmaps[i] = fromPositions(randomPositions(r, int64(1000*(i+1)), 0.05)...)
+	}
+
+	u := NewUnion(bitmaps...)
+	expected := orMany(bitmaps...)
+	require.Equal(expected.Bits(), u.Bits())
+
+	for pos := int64(-1); pos < 5100; pos++ {
+		require.Equal(expected.Get(pos), u.Get(pos), "position %d", pos)
+	}
+
+	require.False(NewUnion().Get(0))
+}
@@ -0,0 +1,171 @@
+package ewah
+
+import "math/bits"
+
+// SetAnywhere sets to 1 the bit at the given position, which, unlike in Set,
+// can be lower than positions already set, as long as it is not before the
+// base. Positions beyond the last bit are set as Set does. Setting a lower
+// bit that is not set yet locates the word holding it and compacts the words
+// again, so the cost is linear in the number of words.
+func (b *Bitmap) SetAnywhere(pos int64) error {
+	if b.frozen {
+		return ErrFrozen
+	}
+
+	if pos >= b.n {
+		return b.Set(pos)
+	}
+
+	if pos < b.base {
+		return ErrInvalidBitSet
+	}
+
+	if !b.Get(pos) {
+		b.updateBit(pos, opOr)
+	}
+	return nil
+}
+
+// SetRange sets to 1 all the bits in the interval [start, end). When the
+// interval is after the last bit, the words are appended as Set does, but the
+// full words of the interval are added as a single run of ones instead of
+// bit by bit. Otherwise, the interval is merged with the bitmap, which costs
+// as much as an OrInPlace. start can't be lower than the base.
+func (b *Bitmap) SetRange(start, end int64) error {
+	if b.frozen {
+		return ErrFrozen
+	}
+
+	if start >= end {
+		return nil
+	}
+
+	if start < b.base {
+		return ErrInvalidBitSet
+	}
+
+	if start < b.n {
+		r := NewWithBase(start)
+		if err := r.SetRange(start, end); err != nil {
+			return err
+		}
+		mergeTo(b, b, r, opOr, nil)
+		return nil
+	}
+
+	if err := b.Set(start); err != nil {
+		return err
+	}
+
+	// the rest of the word of start
+	next := (start/64 + 1) * 64
+	if last := minInt64(end, next); last > start+1 && rlw(b.w[b.lastrlw]).l() > 0 {
+		mask := allones >> uint(start%64+1) &^ (allones >> uint((last-1)%64+1))
+		b.w[len(b.w)-1] |= mask
+		if b.hasCard {
+			b.card += int64(bits.OnesCount64(mask))
+		}
+		b.foldLast()
+	}
+
+	if end > next {
+		b.appendClean(true, (end-next)/64)
+		if rest := (end - next) % 64; rest > 0 {
+			b.appendLiteral(^(allones >> uint(rest)))
+		}
+	}
+
+	b.n = end
+	b.assertInvariants()
+	return nil
+}
+
+// AddMany sets to 1 the bits at the given positions, which must be sorted in
+// ascending order and, as in Set, not lower than the positions already set,
+// although they may be repeated. The positions falling in the same word are
+// set all at once, so the markers are only updated once per word. If a
+// position is out of order, ErrInvalidBitSet is returned and only the
+// positions before it are set.
+func (b *Bitmap) AddMany(positions []int64) error {
+	for i := 0; i < len(positions); {
+		if err := b.Set(positions[i]); err != nil {
+			return err
+		}
+
+		// a position already set before the last one leaves the last word
+		// untouched, so the next positions can't be set along with it
+		if b.n != positions[i]+1 {
+			i++
+			continue
+		}
+
+		var word uint64
+		last := positions[i]
+		for i++; i < len(positions) && positions[i]/64 == last/64 && positions[i] >= last; i++ {
+			last = positions[i]
+			word |= bmask >> uint(last%64)
+		}
+
+		if word != 0 && rlw(b.w[b.lastrlw]).l() > 0 {
+			if b.hasCard {
+				b.card += int64(bits.OnesCount64(word &^ b.w[len(b.w)-1]))
+			}
+			b.w[len(b.w)-1] |= word
+			b.foldLast()
+		}
+		b.n = maxInt64(b.n, last+1)
+	}
+
+	b.assertInvariants()
+	return nil
+}
+
+// Clear sets to 0 the bit at the given position, which can be anywhere in
+// the bitmap. Unsetting a bit in a run of ones splits the run around the
+// literal word holding it, and the words are compacted again, so the cost is
+// linear in the number of words. Clearing a bit that is not set has no
+// effect, and the bitmap keeps its number of bits.
+func (b *Bitmap) Clear(pos int64) error {
+	if b.frozen {
+		return ErrFrozen
+	}
+
+	if pos < b.base || !b.Get(pos) {
+		return nil
+	}
+
+	b.updateBit(pos, opAndNot)
+	return nil
+}
+
+// updateBit makes the bitmap the result of combining it, using op, with a
+// bitmap with only the given position set, which must not be lower than the
+// base. The words of the bitmap are reused as in the in-place operations.
+func (b *Bitmap) updateBit(pos int64, op func(x, y uint64) uint64) {
+	single := NewWithBase(pos)
+	single.appendLiteral(bmask >> uint(pos%64))
+	single.n = pos + 1
+	mergeTo(b, b, single, op, nil)
+}
+
+// Toggle flips the bit at the given position, which can be anywhere in the
+// bitmap except before its base, and returns its new value. Positions beyond
+// the last bit are set as Set does, and the rest are flipped like Clear does,
+// so the cost is linear in the number of words.
+func (b *Bitmap) Toggle(pos int64) (bool, error) {
+	if b.frozen {
+		return false, ErrFrozen
+	}
+
+	if pos >= b.n {
+		return true, b.Set(pos)
+	}
+
+	if pos < b.base {
+		return false, ErrInvalidBitSet
+	}
+
+	bit := b.Get(pos)
+	b.updateBit(pos, opXor)
+	return !bit, nil
+}
@@ -0,0 +1,241 @@
+package ewah
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetAnywhere(t *testing.T) {
+	require := require.New(t)
+	r := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 20; i++ {
+		ps := randomPositions(r, int64(r.Intn(5000)+1), r.Float64())
+		b := New()
+		for _, j := range r.Perm(len(ps)) {
+			require.NoError(b.SetAnywhere(ps[j]))
+		}
+		if len(ps) > 0 {
+			require.NoError(b.SetAnywhere(ps[0]))
+		}
+
+		require.Equal(ps, positions(b))
+		require.True(b.Equal(fromPositions(ps...)))
+		require.NoError(b.checkInvariants())
+	}
+
+	b := NewWithBase(6400)
+	require.NoError(b.SetAnywhere(6500))
+	require.NoError(b.SetAnywhere(6401))
+	require.Equal(ErrInvalidBitSet, b.SetAnywhere(10))
+	require.Equal([]int64{6401, 6500}, positions(b))
+
+	b.Freeze()
+	require.Equal(ErrFrozen, b.SetAnywhere(6402))
+}
+
+func TestSetRange(t *testing.T) {
+	require := require.New(t)
+	r := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 50; i++ {
+		b := New()
+		set := make(map[int64]bool)
+		var n int64
+		for j := 0; j < 20; j++ {
+			start := int64(r.Intn(3000))
+			if r.Intn(2) == 0 {
+				start += b.n
+			}
+			end := start + int64(r.Intn(500))
+			if r.Intn(5) == 0 {
+				end = start + 1
+			}
+
+			require.NoError(b.SetRange(start, end))
+			for p := start; p < end; p++ {
+				set[p] = true
+			}
+			n = maxInt64(n, end)
+		}
+
+		var expected []int64
+		for p := int64(0); p < n; p++ {
+			if set[p] {
+				expected = append(expected, p)
+			}
+		}
+		require.Equal(expected, positions(b))
+		require.True(b.Equal(fromPositions(expected...)))
+		require.Equal(fromPositions(expected...).w, b.w)
+		require.NoError(b.checkInvariants())
+	}
+
+	b := New()
+	require.NoError(b.SetRange(10, 1<<20))
+	require.Equal(int64(1<<20-10), b.Cardinality())
+	require.Len(b.w, 3)
+	require.NoError(b.SetRange(5, 5))
+	require.Equal(int64(1<<20), b.n)
+
+	b = NewWithBase(6400)
+	require.Equal(ErrInvalidBitSet, b.SetRange(10, 7000))
+	require.NoError(b.SetRange(6410, 7000))
+	require.Equal(int64(590), b.Cardinality())
+
+	b.Freeze()
+	require.Equal(ErrFrozen, b.SetRange(8000, 9000))
+}
+
+func TestAddMany(t *testing.T) {
+	require := require.New(t)
+	r := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 30; i++ {
+		ps := randomPositions(r, int64(r.Intn(20000)), r.Float64())
+		b := New()
+		for rest := ps; len(rest) > 0; {
+			k := r.Intn(len(rest) + 1)
+			require.NoError(b.AddMany(rest[:k]))
+			rest = rest[k:]
+		}
+		require.Equal(ps, positions(b))
+		require.NoError(b.checkInvariants())
+	}
+
+	ps := randomPositions(r, 10000, 0.5)
+	b := New()
+	require.NoError(b.AddMany(ps))
+	require.Equal(fromPositions(ps...), b)
+
+	b = New()
+	require.NoError(b.AddMany([]int64{1, 1, 2, 64, 64}))
+	require.Equal([]int64{1, 2, 64}, positions(b))
+	require.Equal(int64(65), b.n)
+
+	b = New()
+	require.Equal(ErrInvalidBitSet, b.AddMany([]int64{1, 5, 3, 100}))
+	require.Equal([]int64{1, 5}, positions(b))
+	require.Equal(ErrInvalidBitSet, b.AddMany([]int64{0}))
+
+	// duplicates of positions set in earlier words
+	b = fromPositions(5, 200)
+	require.Equal(ErrInvalidBitSet, b.AddMany([]int64{5, 6}))
+	require.Equal([]int64{5, 200}, positions(b))
+	require.NoError(b.Validate())
+
+	b = fromPositions(5, 130)
+	require.Equal(ErrInvalidBitSet, b.AddMany([]int64{5, 7}))
+	require.Equal([]int64{5, 130}, positions(b))
+	require.NoError(b.Validate())
+
+	b = fromPositions(5, 130)
+	require.NoError(b.AddMany([]int64{5, 130, 131, 135}))
+	require.Equal([]int64{5, 130, 131, 135}, positions(b))
+	require.NoError(b.Validate())
+
+	b = NewWithBase(6400)
+	require.Equal(ErrInvalidBitSet, b.AddMany([]int64{10}))
+	require.NoError(b.AddMany([]int64{6401, 6402, 6500}))
+	require.Equal([]int64{6401, 6402, 6500}, positions(b))
+
+	b.Freeze()
+	require.Equal(ErrFrozen, b.AddMany([]int64{7000}))
+	require.NoError(b.AddMany(nil))
+}
+
+func TestClear(t *testing.T) {
+	require := require.New(t)
+	r := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 20; i++ {
+		n := int64(r.Intn(5000) + 1)
+		ps := randomPositions(r, n, r.Float64())
+		b := fromPositions(ps...)
+		bits := b.n
+
+		set := make(map[int64]bool)
+		for _, p := range ps {
+			set[p] = true
+		}
+
+		for j := 0; j < 50; j++ {
+			pos := int64(r.Intn(int(n)))
+			require.NoError(b.Clear(pos))
+			delete(set, pos)
+		}
+
+		var expected []int64
+		for _, p := range ps {
+			if set[p] {
+				expected = append(expected, p)
+			}
+		}
+		require.Equal(expected, positions(b))
+		require.Equal(bits, b.n)
+		require.NoError(b.checkInvariants())
+	}
+
+	b := zeroes(1000).Not()
+	require.NoError(b.Clear(500))
+	require.NoError(b.Clear(5000))
+	require.Equal(int64(999), b.Cardinality())
+	require.False(b.Get(500))
+	require.Len(b.w, 4)
+
+	b = NewWithBase(6400)
+	require.NoError(b.Set(6500))
+	require.NoError(b.Clear(10))
+	require.NoError(b.Clear(6500))
+	require.True(b.IsEmpty())
+	require.Equal(int64(6400), b.Base())
+
+	b.Freeze()
+	require.Equal(ErrFrozen, b.Clear(6500))
+}
+
+func TestToggle(t *testing.T) {
+	require := require.New(t)
+	r := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 20; i++ {
+		n := int64(r.Intn(5000) + 1)
+		b := fromPositions(randomPositions(r, n, r.Float64())...)
+
+		set := make(map[int64]bool)
+		for _, p := range positions(b) {
+			set[p] = true
+		}
+
+		for j := 0; j < 50; j++ {
+			pos := int64(r.Intn(int(n) + 100))
+			bit, err := b.Toggle(pos)
+			require.NoError(err)
+			require.Equal(!set[pos], bit)
+			set[pos] = bit
+		}
+
+		for pos := int64(0); pos < b.n; pos++ {
+			require.Equal(set[pos], b.Get(pos), "position %d", pos)
+		}
+		require.NoError(b.checkInvariants())
+	}
+
+	b := NewWithBase(6400)
+	_, err := b.Toggle(10)
+	require.Equal(ErrInvalidBitSet, err)
+
+	bit, err := b.Toggle(6500)
+	require.NoError(err)
+	require.True(bit)
+	bit, err = b.Toggle(6500)
+	require.NoError(err)
+	require.False(bit)
+	require.True(b.IsEmpty())
+
+	b.Freeze()
+	_, err = b.Toggle(6500)
+	require.Equal(ErrFrozen, err)
+}
@@ -0,0 +1,111 @@
+package ewah
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// View is a read-only bitmap backed by its serialized form, as written by
+// Bitmap.Write, such as a region of a memory-mapped file. Words are decoded
+// on demand, so creating a View neither copies the data nor allocates memory
+// proportional to the size of the bitmap. A View never modifies the data, and
+// it is safe to use it from several goroutines at once.
+type View struct {
+	order binary.ByteOrder
+	// words holds the serialized words of the bitmap.
+	words   []byte
+	n       int64
+	lastrlw int
+}
+
+// NewView creates a View over the given serialized bitmap. The data must not
+// be modified while the View is in use.
+func NewView(data []byte, order binary.ByteOrder) (*View, error) {
+	if len(data) < 12 {
+		return nil, fmt.Errorf("bitmap: view needs at least 12 bytes, got %d", len(data))
+	}
+
+	bits := order.Uint32(data)
+	words := int64(order.Uint32(data[4:]))
+	size := 4*3 + words*8
+	if int64(len(data)) < size {
+		return nil, fmt.Errorf("bitmap: view needs %d bytes for %d words, got %d", size, words, len(data))
+	}
+
+	lastrlw := order.Uint32(data[size-4:])
+	if words > 0 && int64(lastrlw) >= words {
+		return nil, fmt.Errorf("bitmap: position of current RLW %d is out of range", lastrlw)
+	}
+
+	return &View{
+		order:   order,
+		words:   data[8 : size-4],
+		n:       int64(bits),
+		lastrlw: int(lastrlw),
+	}, nil
+}
+
+// Bits returns the number of uncompressed bits in the bitmap.
+func (v *View) Bits() uint32 {
+	return uint32(v.n)
+}
+
+// Get returns the bit at the given position, being true 1 and false 0.
+func (v *View) Get(pos int64) bool {
+	if pos < 0 || pos >= v.n {
+		return false
+	}
+
+	var acc int64
+	words := v.len()
+	for i := 0; i < words; {
+		r := rlw(v.word(i))
+		kb := int64(r.k()) * 64
+		if pos < acc+kb {
+			return r.b()
+		}
+		acc += kb
+
+		l := int(r.l())
+		if pos < acc+int64(l)*64 {
+			j := i + 1 + int((pos-acc)/64)
+			if j >= words {
+				return false
+			}
+			return v.word(j)&(bmask>>uint64((pos-acc)%64)) != 0
+		}
+
+		acc += int64(l) * 64
+		i += l + 1
+	}
+
+	return false
+}
+
+// MaterializeMutable returns a Bitmap with a copy of the contents of the view,
+// which can be modified and appended to like any other Bitmap.
+func (v *View) MaterializeMutable() *Bitmap {
+	w := make([]uint64, v.len())
+	for i := range w {
+		w[i] = v.word(i)
+	}
+
+	lastrlw := v.lastrlw
+	if len(w) == 0 {
+		w, lastrlw = nil, -1
+	}
+
+	b := &Bitmap{n: v.n, w: w}
+	b.setLast(lastrlw)
+	return b
+}
+
+// len returns the number of words in the view.
+func (v *View) len() int {
+	return len(v.words) / 8
+}
+
+// word returns the i-th word in the view.
+func (v *View) word(i int) uint64 {
+	return v.order.Uint64(v.words[i*8:])
+}
@@ -0,0 +1,76 @@
+package ewah
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestView(t *testing.T) {
+	require := require.New(t)
+	r := rand.New(rand.NewSource(1))
+
+	for _, order := range []binary.ByteOrder{binary.BigEndian, binary.LittleEndian} {
+		ps := randomPositions(r, 5000, 0.5)
+		b := fromPositions(ps...)
+
+		buf := bytes.NewBuffer(nil)
+		_, err := b.Write(buf, order)
+		require.NoError(err)
+
+		v, err := NewView(buf.Bytes(), order)
+		require.NoError(err)
+		require.Equal(b.Bits(), v.Bits())
+
+		var got []int64
+		for i := int64(0); i < b.n+100; i++ {
+			if v.Get(i) {
+				got = append(got, i)
+			}
+		}
+		require.Equal(ps, got)
+	}
+}
+
+func TestViewMaterializeMutable(t *testing.T) {
+	require := require.New(t)
+
+	b := fromPositions(1, 64, 65, 200)
+	buf := bytes.NewBuffer(nil)
+	_, err := b.Write(buf, binary.BigEndian)
+	require.NoError(err)
+
+	v, err := NewView(buf.Bytes(), binary.BigEndian)
+	require.NoError(err)
+
+	m := v.MaterializeMutable()
+	require.Equal(b, m)
+
+	require.NoError(m.Set(300))
+	require.Equal([]int64{1, 64, 65, 200, 300}, positions(m))
+	require.False(v.Get(300))
+
+	v, err = NewView(make([]byte, 12), binary.BigEndian)
+	require.NoError(err)
+
+	m = v.MaterializeMutable()
+	require.Equal(New(), m)
+	require.NoError(m.Set(3))
+}
+
+func TestNewViewErrors(t *testing.T) {
+	require := require.New(t)
+
+	_, err := NewView([]byte{0, 0}, binary.BigEndian)
+	require.EqualError(err, "bitmap: view needs at least 12 bytes, got 2")
+
+	_, err = NewView([]byte{0, 0, 0, 1, 0, 0, 0, 1, 0, 0, 0, 0}, binary.BigEndian)
+	require.EqualError(err, "bitmap: view needs 20 bytes for 1 words, got 12")
+
+	data := []byte{0, 0, 0, 1, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1}
+	_, err = NewView(data, binary.BigEndian)
+	require.EqualError(err, "bitmap: position of current RLW 1 is out of range")
+}
@@ -0,0 +1,201 @@
+package ewah
+
+import (
+	"fmt"
+	"math/bits"
+)
+
+// groupBits is the number of bits in every group of WAH and Concise words.
+const groupBits = 31
+
+// groupMask has the 31 bits of a group set.
+const groupMask = uint32(1)<<groupBits - 1
+
+// maxWAHFill is the maximum number of groups in a WAH fill word.
+const maxWAHFill = 1<<30 - 1
+
+// ToWAH converts the bitmap to the 32-bit WAH encoding, where every word
+// is either a literal, with the most significant bit unset and the next 31
+// bits of the bitmap, starting from bit 30, or a fill, with the most
+// significant bit set, the value of the fill in bit 30 and the number of
+// groups of 31 bits it covers in the rest. The last group is padded with
+// zeroes. Runs of the bitmap are converted as a whole, without looking at
+// every bit in them.
+func (b *Bitmap) ToWAH() []uint32 {
+	var out []uint32
+	b.groups(&groupBuilder{
+		literal: func(group uint32) {
+			out = append(out, group)
+		},
+		fill: func(bit bool, groups int64) {
+			for groups > 0 {
+				if last := len(out) - 1; last >= 0 && out[last]>>30 == wahFill(bit, 0)>>30 && out[last]&maxWAHFill < maxWAHFill {
+					add := minInt64(groups, int64(maxWAHFill-out[last]&maxWAHFill))
+					out[last] += uint32(add)
+					groups -= add
+					continue
+				}
+				out = append(out, wahFill(bit, 0))
+			}
+		},
+	})
+	return out
+}
+
+// FromWAH creates a bitmap of n bits from its WAH encoding, as described in
+// ToWAH.
+func FromWAH(words []uint32, n int64) (*Bitmap, error) {
+	d := newGroupDecoder(n)
+	for _, w := range words {
+		if w>>31 == 0 {
+			d.addGroup(w)
+		} else {
+			d.addRun(w>>30&1 == 1, int64(w&maxWAHFill)*groupBits)
+		}
+
+		if d.err != nil {
+			return nil, d.err
+		}
+	}
+	return d.bitmap(), nil
+}
+
+func wahFill(bit bool, groups uint32) uint32 {
+	w := uint32(1)<<31 | groups
+	if bit {
+		w |= 1 << 30
+	}
+	return w
+}
+
+// groupBuilder splits the bits of a bitmap in groups of 31 bits, calling
+// literal for groups with both zeroes and ones, with the first bit at bit 30,
+// and fill for runs of whole groups of the same bit.
+type groupBuilder struct {
+	literal func(group uint32)
+	fill    func(bit bool, groups int64)
+	// group holds the bits of the current, incomplete group.
+	group uint32
+	// n is the number of bits in the current group.
+	n int64
+}
+
+// addRun adds count bits with the given value.
+func (g *groupBuilder) addRun(bit bool, count int64) {
+	for count > 0 {
+		if g.n == 0 && count >= groupBits {
+			groups := count / groupBits
+			g.fill(bit, groups)
+			count -= groups * groupBits
+			continue
+		}
+
+		take := minInt64(groupBits-g.n, count)
+		if bit {
+			g.group |= groupMask >> uint64(g.n) &^ (groupMask >> uint64(g.n+take))
+		}
+		g.n += take
+		count -= take
+
+		if g.n == groupBits {
+			g.flush()
+		}
+	}
+}
+
+// addBits adds the first count bits of v, which are its most significant
+// ones.
+func (g *groupBuilder) addBits(v uint64, count int64) {
+	for count > 0 {
+		take := minInt64(groupBits-g.n, count)
+		g.group |= uint32(v>>uint64(64-take)) << uint64(groupBits-g.n-take)
+		v <<= uint64(take)
+		g.n += take
+		count -= take
+
+		if g.n == groupBits {
+			g.flush()
+		}
+	}
+}
+
+// flush emits the current group, which is padded with zeroes if it is not
+// complete.
+func (g *groupBuilder) flush() {
+	switch g.group {
+	case 0:
+		g.fill(false, 1)
+	case groupMask:
+		g.fill(true, 1)
+	default:
+		g.literal(g.group)
+	}
+	g.group, g.n = 0, 0
+}
+
+// groups feeds all the bits of the bitmap to g.
+func (b *Bitmap) groups(g *groupBuilder) {
+	remaining := b.n
+	it := b.runs()
+	for remaining > 0 && !it.done() {
+		if it.run > 0 {
+			count := minInt64(it.run*64, remaining)
+			g.addRun(it.bit, count)
+			remaining -= count
+			it.discard(it.run)
+			continue
+		}
+
+		count := minInt64(64, remaining)
+		g.addBits(it.literal(0), count)
+		remaining -= count
+		it.discard(1)
+	}
+
+	g.addRun(false, remaining)
+	if g.n > 0 {
+		g.flush()
+	}
+}
+
+// groupDecoder builds a bitmap of n bits from groups of 31 bits. Bits beyond
+// n, which pad the last group, must be unset.
+type groupDecoder struct {
+	s   *streamBuilder
+	n   int64
+	err error
+}
+
+func newGroupDecoder(n int64) *groupDecoder {
+	return &groupDecoder{s: newStreamBuilder(), n: n}
+}
+
+// addRun adds count bits with the given value.
+func (d *groupDecoder) addRun(bit bool, count int64) {
+	take := minInt64(count, d.n-d.s.n)
+	if bit && take < count {
+		d.err = fmt.Errorf("bitmap: bit %d is set beyond the %d bits of the bitmap", d.s.n+take, d.n)
+		return
+	}
+	d.s.addRun(bit, take)
+}
+
+// addGroup adds the 31 bits of the given group, starting from bit 30.
+func (d *groupDecoder) addGroup(group uint32) {
+	v := uint64(group&groupMask) << (64 - groupBits)
+	take := minInt64(groupBits, d.n-d.s.n)
+	if extra := v << uint64(take); extra != 0 {
+		d.err = fmt.Errorf("bitmap: bit %d is set beyond the %d bits of the bitmap", d.s.n+take+int64(bits.LeadingZeros64(extra)), d.n)
+		return
+	}
+
+	if take > 0 {
+		d.s.addBits(v, int(take))
+	}
+}
+
+// bitmap returns the decoded bitmap, padded with zeroes up to n bits.
+func (d *groupDecoder) bitmap() *Bitmap {
+	d.s.addRun(false, d.n-d.s.n)
+	return d.s.bitmap()
+}
@@ -0,0 +1,101 @@
+package ewah
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWAH(t *testing.T) {
+	require := require.New(t)
+
+	b := New()
+	require.NoError(b.Set(0))
+	for i := int64(31); i < 62; i++ {
+		require.NoError(b.Set(i))
+	}
+	require.NoError(b.Set(92))
+
+	words := b.ToWAH()
+	require.Equal([]uint32{0x40000000, 0xc0000001, 0x00000001}, words)
+
+	b2, err := FromWAH(words, b.n)
+	require.NoError(err)
+	require.Equal(positions(b), positions(b2))
+	require.Equal(b.n, b2.n)
+
+	b = fromPositions(3)
+	b.extend(124)
+	require.Equal([]uint32{0x08000000, 0x80000003}, b.ToWAH())
+
+	_, err = FromWAH([]uint32{0x40000000, 0xc0000001}, 40)
+	require.EqualError(err, "bitmap: bit 40 is set beyond the 40 bits of the bitmap")
+
+	_, err = FromWAH([]uint32{0x00000001}, 20)
+	require.EqualError(err, "bitmap: bit 30 is set beyond the 20 bits of the bitmap")
+
+	b, err = FromWAH(nil, 100)
+	require.NoError(err)
+	require.Equal(int64(100), b.n)
+	require.Empty(positions(b))
+}
+
+func TestConcise(t *testing.T) {
+	require := require.New(t)
+
+	b := New()
+	require.NoError(b.Set(0))
+	for i := int64(31); i < 62; i++ {
+		require.NoError(b.Set(i))
+	}
+	require.NoError(b.Set(92))
+
+	words := b.ToConcise()
+	require.Equal([]uint32{0x80000001, 0x40000000, 0xc0000000}, words)
+
+	b2, err := FromConcise(words, b.n)
+	require.NoError(err)
+	require.Equal(positions(b), positions(b2))
+
+	// a literal with a single bit set followed by zeroes
+	b = fromPositions(3)
+	b.extend(124)
+	require.Equal([]uint32{0x08000003}, b.ToConcise())
+
+	// a literal with a single bit unset followed by ones
+	b = New()
+	for i := int64(0); i < 31*3; i++ {
+		if i != 5 {
+			require.NoError(b.Set(i))
+		}
+	}
+	require.Equal([]uint32{0x4c000002}, b.ToConcise())
+
+	b2, err = FromConcise([]uint32{0x4c000002}, b.n)
+	require.NoError(err)
+	require.Equal(positions(b), positions(b2))
+
+	_, err = FromConcise([]uint32{0x40000000}, 20)
+	require.EqualError(err, "bitmap: bit 20 is set beyond the 20 bits of the bitmap")
+}
+
+func TestWAHConciseRandom(t *testing.T) {
+	require := require.New(t)
+	r := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 50; i++ {
+		b := fromPositions(randomPositions(r, 20000, r.Float64())...)
+		b.extend(b.n + int64(r.Intn(100)))
+
+		wah, err := FromWAH(b.ToWAH(), b.n)
+		require.NoError(err)
+		require.Equal(b.n, wah.n)
+		require.Equal(positions(b), positions(wah))
+
+		concise, err := FromConcise(b.ToConcise(), b.n)
+		require.NoError(err)
+		require.Equal(b.n, concise.n)
+		require.Equal(positions(b), positions(concise))
+	}
+}
@@ -0,0 +1,52 @@
+package ewah
+
+// Window is a read-only view of the positions in the interval [from, to) of
+// a bitmap, which are seen as the positions from 0 to to-from. Unlike Slice,
+// it shares the words of the bitmap, so creating one is cheap no matter how
+// large the interval is, which makes it a good fit to access the partitions
+// of a large bitmap. The bitmap must not be modified while the view is in
+// use, and several windows over the same bitmap can only be used from
+// different goroutines if the bitmap is frozen.
+type Window struct {
+	b        *Bitmap
+	from, to int64
+}
+
+// Window returns a view of the positions in the interval [from, to) of the
+// bitmap.
+func (b *Bitmap) Window(from, to int64) *Window {
+	from = maxInt64(from, 0)
+	return &Window{b: b, from: from, to: maxInt64(to, from)}
+}
+
+// Bits returns the number of bits in the window.
+func (w *Window) Bits() uint32 {
+	return uint32(w.to - w.from)
+}
+
+// Get returns the bit at the given position of the window, being true 1 and
+// false 0.
+func (w *Window) Get(pos int64) bool {
+	if pos < 0 || pos >= w.to-w.from {
+		return false
+	}
+	return w.b.Get(w.from + pos)
+}
+
+// Iterator returns an iterator over the positions of the set bits in the
+// window.
+func (w *Window) Iterator() *Iterator {
+	it := w.b.IteratorRange(w.from, w.to)
+	it.offset = w.from
+	return it
+}
+
+// Slice returns a new bitmap with a copy of the positions in the interval
+// [from, to) of the bitmap, which are moved to the positions from 0 to
+// to-from. Runs are copied as a whole, without looking at every bit in them.
+func (b *Bitmap) Slice(from, to int64) *Bitmap {
+	from = maxInt64(from, 0)
+	s := newStreamBuilder()
+	s.addSlice(b, from, maxInt64(to, from))
+	return s.bitmap()
+}
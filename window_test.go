@@ -0,0 +1,57 @@
+package ewah
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWindow(t *testing.T) {
+	require := require.New(t)
+	r := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 50; i++ {
+		ps := randomPositions(r, 10000, r.Float64())
+		b := fromPositions(ps...)
+
+		from := int64(r.Intn(11000))
+		to := from + int64(r.Intn(3000))
+
+		var expected []int64
+		for _, p := range ps {
+			if p >= from && p < to {
+				expected = append(expected, p-from)
+			}
+		}
+
+		w := b.Window(from, to)
+		require.Equal(uint32(to-from), w.Bits())
+		require.Equal(expected, iterate(w.Iterator()))
+		for pos := int64(-1); pos <= to-from; pos++ {
+			require.Equal(b.Get(from+pos) && pos >= 0 && pos < to-from, w.Get(pos))
+		}
+
+		s := b.Slice(from, to)
+		require.Equal(to-from, s.n)
+		require.Equal(expected, positions(s))
+	}
+}
+
+func TestSlice(t *testing.T) {
+	require := require.New(t)
+
+	b := New()
+	for i := int64(0); i < 64*5; i++ {
+		require.NoError(b.Set(i))
+	}
+	require.NoError(b.Set(64*7 + 3))
+
+	s := b.Slice(60, 64*8)
+	require.Equal(int64(64*8-60), s.n)
+	require.Equal(int64(64*7+3-60), iterate(s.Iterator())[64*5-60])
+	require.Equal(uint64(newRlw(true, 4, 1)), s.w[0])
+
+	require.Equal(int64(0), b.Slice(10, 5).n)
+	require.Equal(int64(0), b.Window(10, 5).to-b.Window(10, 5).from)
+}
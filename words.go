@@ -0,0 +1,580 @@
+package ewah
+
+import (
+	"math"
+	"math/bits"
+)
+
+// runIterator walks the words of a bitmap marker by marker. At any point it
+// exposes the clean words left in the current marker (run words of bit) and
+// the literal words that follow them.
+type runIterator struct {
+	w []uint64
+	// next is the index of the next marker to load.
+	next int
+	// bit is the value repeated in the current clean run.
+	bit bool
+	// run is the number of clean words left in the current marker.
+	run int64
+	// lit is the index of the next literal word.
+	lit int
+	// lits is the number of literal words left in the current marker.
+	lits int
+	// flip is xored with every word read from w.
+	flip uint64
+	// left is the number of words left to read from w.
+	left int64
+	// suffix holds the encoded words read once w has been consumed.
+	suffix []uint64
+}
+
+func newRunIterator(w []uint64) runIterator {
+	it := runIterator{w: w, left: math.MaxInt64}
+	it.advance()
+	return it
+}
+
+// runs returns an iterator over the words of the bitmap starting at position
+// 0, where the positions before the base of the bitmap are seen as a run of
+// zeroes.
+func (b *Bitmap) runs() runIterator {
+	return b.runsFrom(0)
+}
+
+// runsFrom is like runs, but starts at the given position, which must be a
+// multiple of 64 not greater than the base of the bitmap.
+func (b *Bitmap) runsFrom(pos int64) runIterator {
+	it := runIterator{w: b.w, run: (b.base - pos) / 64, left: math.MaxInt64}
+	it.advance()
+	return it
+}
+
+// advance loads markers until one with words left is found or there are no
+// more markers. Once the words in w are consumed, or left reaches zero, the
+// iterator moves on to the suffix, if any.
+func (it *runIterator) advance() {
+	for it.run == 0 && it.lits == 0 {
+		if it.next >= len(it.w) || it.left <= 0 {
+			if it.suffix == nil {
+				return
+			}
+
+			it.w, it.next, it.flip, it.left, it.suffix = it.suffix, 0, 0, math.MaxInt64, nil
+			continue
+		}
+
+		r := rlw(it.w[it.next])
+		it.bit = r.b() != (it.flip != 0)
+		it.run = minInt64(int64(r.k()), it.left)
+		it.left -= it.run
+		it.lit = it.next + 1
+		it.lits = int(r.l())
+		if it.lit+it.lits > len(it.w) {
+			it.lits = len(it.w) - it.lit
+		}
+		it.next = it.lit + it.lits
+
+		if int64(it.lits) > it.left {
+			it.lits = int(it.left)
+		}
+		it.left -= int64(it.lits)
+	}
+}
+
+// done reports whether all words have been consumed.
+func (it *runIterator) done() bool {
+	return it.run == 0 && it.lits == 0
+}
+
+// onlyZeroes reports whether all the words left are zeroes, which is the
+// case when they are done or the current run of zeroes is the last thing in
+// the words.
+func (it *runIterator) onlyZeroes() bool {
+	if it.done() {
+		return true
+	}
+	return !it.bit && it.lits == 0 && (it.next >= len(it.w) || it.left <= 0) && it.suffix == nil
+}
+
+// literal returns the i-th literal word left in the current marker.
+func (it *runIterator) literal(i int) uint64 {
+	return it.w[it.lit+i] ^ it.flip
+}
+
+// discard skips the next n words.
+func (it *runIterator) discard(n int64) {
+	for n > 0 && !it.done() {
+		if it.run > 0 {
+			d := minInt64(n, it.run)
+			it.run -= d
+			n -= d
+		} else {
+			d := int(minInt64(n, int64(it.lits)))
+			it.lit += d
+			it.lits -= d
+			n -= int64(d)
+		}
+
+		if it.done() {
+			it.advance()
+		}
+	}
+}
+
+// bitIterator returns the positions of the set bits of a bitmap one by one
+// in ascending order.
+type bitIterator struct {
+	it runIterator
+	// offset is the position of the first bit of the words left in it.
+	offset int64
+	// pos and end delimit the positions left in the current run of ones.
+	pos, end int64
+	// word holds the bits left in the current literal, which starts at
+	// wordOffset.
+	word       uint64
+	wordOffset int64
+}
+
+func (b *Bitmap) bits() *bitIterator {
+	return &bitIterator{it: b.runs()}
+}
+
+// next returns the next set position, or false if there are none left.
+func (i *bitIterator) next() (int64, bool) {
+	for {
+		if i.pos < i.end {
+			i.pos++
+			return i.pos - 1, true
+		}
+
+		if i.word != 0 {
+			idx := bits.LeadingZeros64(i.word)
+			i.word &^= bmask >> uint(idx)
+			return i.wordOffset + int64(idx), true
+		}
+
+		if i.it.done() {
+			return 0, false
+		}
+
+		if i.it.run > 0 {
+			if i.it.bit {
+				i.pos, i.end = i.offset, i.offset+i.it.run*64
+			}
+			i.offset += i.it.run * 64
+			i.it.discard(i.it.run)
+			continue
+		}
+
+		i.word, i.wordOffset = i.it.literal(0), i.offset
+		i.offset += 64
+		i.it.discard(1)
+	}
+}
+
+// seek skips all set positions lower than min, jumping over whole runs and
+// literal words whenever possible.
+func (i *bitIterator) seek(min int64) {
+	if i.pos < i.end {
+		if min > i.pos {
+			i.pos = minInt64(min, i.end)
+		}
+
+		if i.pos < i.end {
+			return
+		}
+	}
+
+	if i.word != 0 {
+		if min >= i.wordOffset+64 {
+			i.word = 0
+		} else if min > i.wordOffset {
+			i.word &= allones >> uint64(min-i.wordOffset)
+		}
+
+		if i.word != 0 {
+			return
+		}
+	}
+
+	for !i.it.done() {
+		if i.it.run > 0 {
+			end := i.offset + i.it.run*64
+			if i.it.bit && end > min {
+				i.pos, i.end = maxInt64(i.offset, min), end
+			}
+			i.offset = end
+			i.it.discard(i.it.run)
+
+			if i.pos < i.end {
+				return
+			}
+			continue
+		}
+
+		if skip := minInt64((min-i.offset)/64, int64(i.it.lits)); skip > 0 {
+			i.offset += skip * 64
+			i.it.discard(skip)
+			continue
+		}
+
+		i.word, i.wordOffset = i.it.literal(0), i.offset
+		if min > i.offset {
+			i.word &= allones >> uint64(min-i.offset)
+		}
+		i.offset += 64
+		i.it.discard(1)
+
+		if i.word != 0 {
+			return
+		}
+	}
+}
+
+// cardinality returns the number of set bits.
+func (b *Bitmap) cardinality() int64 {
+	var count int64
+	it := b.runs()
+	for !it.done() {
+		if it.bit {
+			count += it.run * 64
+		}
+
+		for i := 0; i < it.lits; i++ {
+			count += int64(bits.OnesCount64(it.literal(i)))
+		}
+		it.discard(it.run + int64(it.lits))
+	}
+	return count
+}
+
+// each calls fn with the position of every set bit in ascending order until
+// fn returns false.
+func (b *Bitmap) each(fn func(pos int64) bool) {
+	it := b.runs()
+	var offset int64
+	for !it.done() {
+		if it.bit {
+			end := offset + it.run*64
+			for pos := offset; pos < end; pos++ {
+				if !fn(pos) {
+					return
+				}
+			}
+		}
+		offset += it.run * 64
+
+		for i := 0; i < it.lits; i++ {
+			for word := it.literal(i); word != 0; {
+				idx := bits.LeadingZeros64(word)
+				if !fn(offset + int64(idx)) {
+					return
+				}
+				word &^= bmask >> uint(idx)
+			}
+			offset += 64
+		}
+
+		it.discard(it.run + int64(it.lits))
+	}
+}
+
+// extend grows the bitmap to n bits. The new bits are all unset.
+func (b *Bitmap) extend(n int64) {
+	if n <= b.n {
+		return
+	}
+
+	b.appendClean(false, wordsFor(n)-wordsFor(b.n))
+	b.n = n
+}
+
+// appendClean appends n clean words of the given bit, extending the current
+// marker when possible.
+func (b *Bitmap) appendClean(bit bool, n int64) {
+	if bit && b.hasCard {
+		b.card += n * 64
+	}
+
+	for n > 0 {
+		if b.lastrlw >= 0 {
+			r := rlw(b.w[b.lastrlw])
+			if r.l() == 0 && r.k() == 0 && b.mergeLast(bit) {
+				continue
+			}
+
+			if r.l() == 0 && (r.k() == 0 || r.b() == bit) && r.k() < math.MaxUint32 {
+				add := minInt64(n, int64(math.MaxUint32-r.k()))
+				b.w[b.lastrlw] = uint64(newRlw(bit, r.k()+uint32(add), 0))
+				n -= add
+				continue
+			}
+		}
+
+		b.newMarker()
+	}
+}
+
+// mergeLast removes the last marker, which must be empty, if the previous
+// one has a run of the given bit with room for more words and no literals,
+// so clean words can be added to it instead of leaving two runs of the same
+// bit one after the other. It reports whether the marker was removed.
+func (b *Bitmap) mergeLast(bit bool) bool {
+	if b.prevrlw < 0 || b.prevrlw != b.lastrlw-1 {
+		return false
+	}
+
+	if prev := rlw(b.w[b.prevrlw]); prev.k() > 0 && prev.b() != bit || prev.k() == math.MaxUint32 {
+		return false
+	}
+
+	b.dropLast()
+	return true
+}
+
+// dropLast removes the last marker, which must be empty, making the previous
+// one the last.
+func (b *Bitmap) dropLast() {
+	b.w = b.w[:b.lastrlw]
+	b.setLast(b.prevrlw)
+}
+
+// setLast makes the marker at i the last one, walking the markers before it
+// to find the previous one and the offset of its first word.
+func (b *Bitmap) setLast(i int) {
+	b.lastrlw, b.prevrlw, b.lastoff = i, -1, 0
+	for j := 0; j < i && j < len(b.w); j += int(rlw(b.w[j]).l()) + 1 {
+		r := rlw(b.w[j])
+		b.prevrlw = j
+		b.lastoff += (int64(r.k()) + int64(r.l())) * 64
+	}
+}
+
+// newMarker appends an empty marker, which becomes the last one.
+func (b *Bitmap) newMarker() {
+	if b.lastrlw >= 0 {
+		r := rlw(b.w[b.lastrlw])
+		b.lastoff += (int64(r.k()) + int64(r.l())) * 64
+	}
+
+	b.w = append(b.w, uint64(newRlw(false, 0, 0)))
+	b.prevrlw, b.lastrlw = b.lastrlw, len(b.w)-1
+}
+
+// appendLiteral appends a literal word. Words that are all zeroes or all ones
+// are appended as clean words instead.
+func (b *Bitmap) appendLiteral(word uint64) {
+	switch word {
+	case 0:
+		b.appendClean(false, 1)
+		return
+	case allones:
+		b.appendClean(true, 1)
+		return
+	}
+
+	// a literal after an empty marker goes to the previous one instead, as
+	// long as it has room for it
+	if b.lastrlw > 0 && b.prevrlw >= 0 && rlw(b.w[b.lastrlw]).k() == 0 && rlw(b.w[b.lastrlw]).l() == 0 {
+		if l := rlw(b.w[b.prevrlw]).l(); b.prevrlw+int(l)+1 == b.lastrlw && l < maxUint31 {
+			b.dropLast()
+		}
+	}
+
+	if b.lastrlw < 0 || rlw(b.w[b.lastrlw]).l() >= maxUint31 {
+		b.newMarker()
+	}
+
+	r := rlw(b.w[b.lastrlw])
+	r.setl(r.l() + 1)
+	b.w[b.lastrlw] = uint64(r)
+	b.w = append(b.w, word)
+	if b.hasCard {
+		b.card += int64(bits.OnesCount64(word))
+	}
+}
+
+// foldLast turns the last word into a clean word if it is a literal whose
+// bits have become all zeroes or all ones, merging it with the previous run
+// if possible, so the encoding stays minimal as bits are modified.
+func (b *Bitmap) foldLast() {
+	if b.lastrlw < 0 || rlw(b.w[b.lastrlw]).l() == 0 {
+		return
+	}
+
+	if word := b.w[len(b.w)-1]; word == 0 || word == allones {
+		b.popLiteral()
+		b.appendClean(word == allones, 1)
+	}
+}
+
+// appendWords appends n copies of word.
+func (b *Bitmap) appendWords(word uint64, n int64) {
+	if word == 0 || word == allones {
+		b.appendClean(word == allones, n)
+		return
+	}
+
+	for ; n > 0; n-- {
+		b.appendLiteral(word)
+	}
+}
+
+// popLiteral removes the last word, which must be a literal of the last
+// marker.
+func (b *Bitmap) popLiteral() {
+	if b.hasCard {
+		b.card -= int64(bits.OnesCount64(b.w[len(b.w)-1]))
+	}
+
+	r := rlw(b.w[b.lastrlw])
+	r.setl(r.l() - 1)
+	b.w[b.lastrlw] = uint64(r)
+	b.w = b.w[:len(b.w)-1]
+}
+
+// trimTail clears the bits at or beyond n in the last word, so no bit
+// outside the bitmap is ever set.
+func (b *Bitmap) trimTail() {
+	if b.n%64 == 0 || b.lastrlw < 0 {
+		return
+	}
+
+	mask := allones << (64 - uint64(b.n%64))
+	r := rlw(b.w[b.lastrlw])
+	if r.l() > 0 {
+		last := len(b.w) - 1
+		if word := b.w[last] & mask; word != b.w[last] {
+			b.popLiteral()
+			b.appendLiteral(word)
+		}
+	} else if r.b() && r.k() > 0 {
+		// a marker with no clean words is always written as a run of zeroes
+		b.w[b.lastrlw] = uint64(newRlw(r.k() > 1, r.k()-1, 0))
+		if b.hasCard {
+			b.card -= 64
+		}
+		b.appendLiteral(mask)
+	}
+}
+
+// streamBuilder builds a bitmap from a stream of bits given in order,
+// starting at position 0.
+type streamBuilder struct {
+	b *Bitmap
+	// word holds the bits of the current, incomplete word.
+	word uint64
+	// n is the number of bits added so far.
+	n int64
+}
+
+func newStreamBuilder() *streamBuilder {
+	return &streamBuilder{b: New()}
+}
+
+// addRun adds count bits with the given value.
+func (s *streamBuilder) addRun(bit bool, count int64) {
+	for count > 0 {
+		off := s.n % 64
+		if off == 0 && count >= 64 {
+			words := count / 64
+			s.b.appendClean(bit, words)
+			s.n += words * 64
+			count -= words * 64
+			continue
+		}
+
+		take := minInt64(64-off, count)
+		if bit {
+			s.word |= allones >> uint64(off) &^ (allones >> uint64(off+take))
+		}
+		s.n += take
+		count -= take
+
+		if s.n%64 == 0 {
+			s.b.appendLiteral(s.word)
+			s.word = 0
+		}
+	}
+}
+
+// addBits adds the first count bits of v, which are its most significant
+// ones. The rest of the bits of v must be unset.
+func (s *streamBuilder) addBits(v uint64, count int) {
+	off := uint64(s.n % 64)
+	s.word |= v >> off
+	s.n += int64(count)
+	if off+uint64(count) >= 64 {
+		s.b.appendLiteral(s.word)
+		s.word = 0
+		if off > 0 {
+			s.word = v << (64 - off)
+		}
+	}
+}
+
+// addSlice adds the bits in the interval [from, to) of b, with 0 <= from <=
+// to. Runs are added as a whole, and so are the literals of b when from and
+// the bits added so far are multiples of 64.
+func (s *streamBuilder) addSlice(b *Bitmap, from, to int64) {
+	remaining := to - from
+	it := b.runs()
+	it.discard(from / 64)
+	off := from % 64
+	for remaining > 0 && !it.done() {
+		if it.run > 0 {
+			count := minInt64(it.run*64-off, remaining)
+			s.addRun(it.bit, count)
+			remaining -= count
+			it.discard(it.run)
+			off = 0
+			continue
+		}
+
+		count := minInt64(64-off, remaining)
+		s.addBits(it.literal(0)<<uint64(off)&^(allones>>uint64(count)), int(count))
+		remaining -= count
+		it.discard(1)
+		off = 0
+	}
+
+	s.addRun(false, remaining)
+}
+
+// bitmap returns the built bitmap, which has as many bits as were added.
+func (s *streamBuilder) bitmap() *Bitmap {
+	if s.n%64 != 0 {
+		s.b.appendLiteral(s.word)
+		s.word = 0
+	}
+
+	s.b.n = s.n
+	return s.b
+}
+
+// fill returns the clean word made of the given bit.
+func fill(bit bool) uint64 {
+	if bit {
+		return allones
+	}
+	return 0
+}
+
+// wordsFor returns the number of words needed to hold n bits.
+func wordsFor(n int64) int64 {
+	return (n + 63) / 64
+}
+
+func minInt64(a, b int64) int64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt64(a, b int64) int64 {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,43 @@
+package ewah
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrimTailLastRun(t *testing.T) {
+	require := require.New(t)
+
+	// a run of zeroes followed by a run of ones cut in its only word
+	b := New()
+	for i := int64(64); i < 128; i++ {
+		require.NoError(b.Set(i))
+	}
+	require.Equal([]uint64{uint64(newRlw(false, 1, 0)), uint64(newRlw(true, 1, 0))}, b.w)
+
+	b.n = 100
+	b.trimTail()
+	require.Equal([]uint64{uint64(newRlw(false, 1, 1)), ^(allones >> 36)}, b.w)
+	require.Equal(0, b.lastrlw)
+	require.Equal([]int64{64, 65, 66}, positions(b)[:3])
+	require.Len(positions(b), 36)
+}
+
+func TestAppendLiteralAfterEmptyMarker(t *testing.T) {
+	require := require.New(t)
+
+	// the run of ones cut by trimTail comes after a marker with literals
+	b := New()
+	require.NoError(b.Set(5))
+	for i := int64(64); i < 128; i++ {
+		require.NoError(b.Set(i))
+	}
+	require.Equal([]uint64{uint64(newRlw(false, 0, 1)), bmask >> 5, uint64(newRlw(true, 1, 0))}, b.w)
+
+	b.n = 100
+	b.trimTail()
+	require.Equal([]uint64{uint64(newRlw(false, 0, 2)), bmask >> 5, ^(allones >> 36)}, b.w)
+	require.Equal(0, b.lastrlw)
+	require.Equal(-1, b.prevrlw)
+}